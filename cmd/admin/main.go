@@ -2,25 +2,54 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"text/tabwriter"
 	"time"
+	"tritontube/internal/certs"
 	"tritontube/internal/proto"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 func main() {
-	if len(os.Args) < 3 { // Minimum 3 args: program, command, server_address
+	// TLS options come before the command
+	tlsFiles := certs.Flags()
+	flag.DurationVar(&timeout, "timeout", 0, "How long to wait for the server to answer (default 1h for add and remove, which wait for files to migrate, and 10s or 1m for other commands)")
+	flag.Usage = printUsageAndExit
+	flag.Parse()
+	args := append([]string{os.Args[0]}, flag.Args()...)
+
+	if len(args) < 3 { // Minimum 3 args: program, command, server_address
 		printUsageAndExit()
 	}
 
-	cmd := os.Args[1]
-	serverAddr := os.Args[2]
+	cmd := args[1]
+	serverAddr := args[2]
+
+	// plan takes the operation before the server address
+	if cmd == "plan" {
+		if len(args) != 5 {
+			fmt.Println("Usage: plan add|remove <server_address> <node_address>")
+			os.Exit(1)
+		}
+		serverAddr = args[3]
+	}
 
-	conn, err := grpc.NewClient(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := tlsFiles.ClientCredentials()
+	if err != nil {
+		log.Fatalf("Failed to load TLS certificates: %v", err)
+	}
+	options := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	// The token comes from the environment so it stays out of ps
+	if token := os.Getenv("TRITONTUBE_ADMIN_TOKEN"); token != "" {
+		options = append(options, grpc.WithPerRPCCredentials(bearerToken{token: token, requireTLS: tlsFiles.Enabled()}))
+	}
+	conn, err := grpc.NewClient(serverAddr, options...)
 	if err != nil {
 		log.Fatalf("Failed to connect to server: %v", err)
 	}
@@ -30,39 +59,91 @@ func main() {
 
 	switch cmd {
 	case "add":
-		if len(os.Args) != 4 {
+		if len(args) != 4 {
 			fmt.Println("Usage: add <server_address> <node_address>")
 			os.Exit(1)
 		}
-		addNode(client, os.Args[3])
+		addNode(client, args[3])
 	case "remove":
-		if len(os.Args) != 4 {
+		if len(args) != 4 {
 			fmt.Println("Usage: remove <server_address> <node_address>")
 			os.Exit(1)
 		}
-		removeNode(client, os.Args[3])
+		removeNode(client, args[3])
 	case "list":
-		if len(os.Args) != 3 {
-			fmt.Println("Usage: list <server_address>")
+		if len(args) != 3 && (len(args) != 4 || args[3] != "-json") {
+			fmt.Println("Usage: list <server_address> [-json]")
+			os.Exit(1)
+		}
+		listNodes(client, len(args) == 4)
+	case "drain":
+		if len(args) != 4 {
+			fmt.Println("Usage: drain <server_address> <node_address>")
+			os.Exit(1)
+		}
+		drainNode(client, args[3])
+	case "status":
+		if len(args) != 3 {
+			fmt.Println("Usage: status <server_address>")
 			os.Exit(1)
 		}
-		listNodes(client)
+		clusterStatus(client)
+	case "plan":
+		planTopologyChange(client, args[2], args[4])
 	default:
 		fmt.Printf("Unknown command: %s\n", cmd)
 		printUsageAndExit()
 	}
 }
 
+// timeout overrides the time each command waits for the server if set.
+var timeout time.Duration
+
+// commandContext limits a call to the server to the -timeout flag, or to
+// fallback if it is not set.
+func commandContext(fallback time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		fallback = timeout
+	}
+	return context.WithTimeout(context.Background(), fallback)
+}
+
+// bearerToken sends an admin token with every call. With requireTLS set,
+// gRPC refuses to send it over a connection that is not encrypted.
+type bearerToken struct {
+	token      string
+	requireTLS bool
+}
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
+
 func printUsageAndExit() {
-	fmt.Println("Usage:")
+	fmt.Println("Usage: admin [-tls-cert FILE -tls-key FILE -tls-ca FILE] [-timeout DURATION] <command> ...")
+	fmt.Println()
+	fmt.Println("Commands:")
 	fmt.Println("  add <server_address> <node_address>     - Add a node to the cluster")
 	fmt.Println("  remove <server_address> <node_address>  - Remove a node from the cluster")
-	fmt.Println("  list <server_address>                   - List all nodes in the cluster")
+	fmt.Println("  list <server_address> [-json]           - List all nodes in the cluster")
+	fmt.Println("  drain <server_address> <node_address>   - Move a node's files off it, then remove it")
+	fmt.Println("  status <server_address>                 - Show active nodes and drain progress")
+	fmt.Println("  plan add|remove <server_address> <node_address>")
+	fmt.Println("                                          - Preview the files an add or remove would migrate")
+	fmt.Println()
+	fmt.Println("Options:")
+	flag.PrintDefaults()
+	fmt.Println()
+	fmt.Println("Set TRITONTUBE_ADMIN_TOKEN to authenticate with a bearer token.")
 	os.Exit(1)
 }
 
 func addNode(client proto.VideoContentAdminServiceClient, nodeAddr string) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := commandContext(time.Hour)
 	defer cancel()
 
 	response, err := client.AddNode(ctx, &proto.AddNodeRequest{
@@ -77,7 +158,7 @@ func addNode(client proto.VideoContentAdminServiceClient, nodeAddr string) {
 }
 
 func removeNode(client proto.VideoContentAdminServiceClient, nodeAddr string) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := commandContext(time.Hour)
 	defer cancel()
 
 	response, err := client.RemoveNode(ctx, &proto.RemoveNodeRequest{
@@ -91,8 +172,8 @@ func removeNode(client proto.VideoContentAdminServiceClient, nodeAddr string) {
 	fmt.Printf("Number of files migrated: %d\n", response.MigratedFileCount)
 }
 
-func listNodes(client proto.VideoContentAdminServiceClient) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+func listNodes(client proto.VideoContentAdminServiceClient, asJSON bool) {
+	ctx, cancel := commandContext(10 * time.Second)
 	defer cancel()
 
 	response, err := client.ListNodes(ctx, &proto.ListNodesRequest{})
@@ -100,12 +181,167 @@ func listNodes(client proto.VideoContentAdminServiceClient) {
 		log.Fatalf("ListNodes RPC failed: %v", err)
 	}
 
+	if asJSON {
+		out, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(response)
+		if err != nil {
+			log.Fatalf("Failed to encode response: %v", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
 	fmt.Println("Storage cluster nodes:")
-	if len(response.Nodes) == 0 {
+	if len(response.NodeInfos) == 0 {
 		fmt.Println("  No nodes in cluster")
-	} else {
-		for _, node := range response.Nodes {
-			fmt.Printf("  - %s\n", node)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NODE\tHEALTH\tRING POSITION\tSHARE\tFILES\tUSED\tQUOTA\tFREE\tUPTIME\tVERSION")
+	for _, node := range response.NodeInfos {
+		health := "unknown"
+		if node.Health != proto.NodeHealth_HEALTH_UNKNOWN {
+			health = strings.ToLower(node.Health.String())
+		}
+		if node.Draining {
+			health += ",draining"
+		}
+		if node.Full {
+			health += ",full"
+		}
+
+		quota := "-"
+		if node.QuotaBytes > 0 {
+			quota = formatBytes(node.QuotaBytes)
+		}
+
+		if node.StatusError != "" {
+			fmt.Fprintf(w, "  %s\t%s\t%016x\t%.1f%%\t-\t-\t-\t-\t-\t-\n", node.NodeAddress, health, node.RingPosition, node.KeySpaceShare*100)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%016x\t%.1f%%\t%d\t%s\t%s\t%s\t%s\t%s\n", node.NodeAddress, health, node.RingPosition, node.KeySpaceShare*100,
+			node.FileCount, formatBytes(node.BytesUsed), quota, formatBytes(node.BytesFree), time.Duration(node.UptimeSeconds)*time.Second, node.Version)
+	}
+	w.Flush()
+
+	for _, node := range response.NodeInfos {
+		if node.StatusError != "" {
+			fmt.Printf("  %s: status unavailable: %s\n", node.NodeAddress, node.StatusError)
+		} else if node.LastError != "" {
+			fmt.Printf("  %s: last health check error: %s\n", node.NodeAddress, node.LastError)
+		}
+	}
+}
+
+// formatBytes renders a byte count using binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func drainNode(client proto.VideoContentAdminServiceClient, nodeAddr string) {
+	ctx, cancel := commandContext(10 * time.Second)
+	defer cancel()
+
+	response, err := client.DrainNode(ctx, &proto.DrainNodeRequest{
+		NodeAddress: nodeAddr,
+	})
+	if err != nil {
+		log.Fatalf("DrainNode RPC failed: %v", err)
+	}
+
+	fmt.Printf("Started draining node: %s\n", nodeAddr)
+	fmt.Printf("Files to migrate: %d (%d bytes)\n", response.FileCount, response.ByteCount)
+	fmt.Println("Run the status command to follow progress")
+}
+
+func clusterStatus(client proto.VideoContentAdminServiceClient) {
+	ctx, cancel := commandContext(10 * time.Second)
+	defer cancel()
+
+	response, err := client.GetClusterStatus(ctx, &proto.GetClusterStatusRequest{})
+	if err != nil {
+		log.Fatalf("GetClusterStatus RPC failed: %v", err)
+	}
+
+	fmt.Println("Active nodes:")
+	if len(response.ActiveNodes) == 0 {
+		fmt.Println("  No active nodes")
+	}
+	for _, node := range response.ActiveNodes {
+		fmt.Printf("  - %s\n", node)
+	}
+
+	fmt.Println("Drains:")
+	if len(response.Drains) == 0 {
+		fmt.Println("  None")
+	}
+	for _, drain := range response.Drains {
+		progress := 100.0
+		if drain.TotalByteCount > 0 {
+			progress = float64(drain.MigratedByteCount) / float64(drain.TotalByteCount) * 100
+		}
+		fmt.Printf("  - %s: %s, %d/%d files, %d/%d bytes (%.1f%%)\n", drain.NodeAddress, strings.ToLower(drain.State.String()),
+			drain.MigratedFileCount, drain.TotalFileCount, drain.MigratedByteCount, drain.TotalByteCount, progress)
+		if drain.Error != "" {
+			fmt.Printf("    error: %s\n", drain.Error)
+		}
+	}
+}
+
+func planTopologyChange(client proto.VideoContentAdminServiceClient, operation string, nodeAddr string) {
+	var op proto.TopologyOperation
+	switch operation {
+	case "add":
+		op = proto.TopologyOperation_ADD
+	case "remove":
+		op = proto.TopologyOperation_REMOVE
+	default:
+		fmt.Printf("Unknown plan operation: %s\n", operation)
+		printUsageAndExit()
+	}
+
+	ctx, cancel := commandContext(time.Minute)
+	defer cancel()
+
+	response, err := client.PlanTopologyChange(ctx, &proto.PlanTopologyChangeRequest{
+		Operation:   op,
+		NodeAddress: nodeAddr,
+	})
+	if err != nil {
+		log.Fatalf("PlanTopologyChange RPC failed: %v", err)
+	}
+
+	fmt.Printf("Plan for %s node: %s (nothing has been moved)\n", operation, nodeAddr)
+	fmt.Printf("Files to migrate: %d (%d bytes)\n", response.TotalFileCount, response.TotalByteCount)
+
+	fmt.Println("Transfers:")
+	if len(response.Transfers) == 0 {
+		fmt.Println("  None")
+	}
+	for _, transfer := range response.Transfers {
+		fmt.Printf("  %s -> %s: %d files, %d bytes\n", transfer.Source, transfer.Destination, transfer.FileCount, transfer.ByteCount)
+	}
+
+	var totalBytes int64
+	for _, load := range response.NodeLoads {
+		totalBytes += load.ByteCount
+	}
+
+	fmt.Println("Resulting load:")
+	for _, load := range response.NodeLoads {
+		share := 0.0
+		if totalBytes > 0 {
+			share = float64(load.ByteCount) / float64(totalBytes) * 100
 		}
+		fmt.Printf("  - %s: %d files, %d bytes (%.1f%%)\n", load.NodeAddress, load.FileCount, load.ByteCount, share)
 	}
 }
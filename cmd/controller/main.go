@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"tritontube/internal/certs"
+	"tritontube/internal/logging"
+	"tritontube/internal/metrics"
+	"tritontube/internal/tracing"
+	"tritontube/internal/web"
+)
+
+func printUsage() {
+	fmt.Println("Usage: controller [OPTIONS] ADMIN_ADDRESS STORAGE_SERVERS")
+	fmt.Println()
+	fmt.Println("Owns the hash ring: serves the admin API, rebalances storage nodes and")
+	fmt.Println("pushes ring changes to web servers started with the controller content type.")
+	fmt.Println()
+	fmt.Println("Arguments:")
+	fmt.Println("  ADMIN_ADDRESS         Address to serve the admin API on (e.g., localhost:8081)")
+	fmt.Println("  STORAGE_SERVERS       Comma-separated addresses of the initial storage servers")
+	fmt.Println()
+	fmt.Println("Options:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	migrationParallelism := flag.Int("migration-parallelism", 4, "Number of files a storage node sends at once while rebalancing")
+	migrationRate := flag.Int64("migration-rate", 0, "Maximum bytes per second a storage node sends while rebalancing (0 for unlimited)")
+	healthInterval := flag.Duration("health-interval", 5*time.Second, "How often to probe storage nodes")
+	healthThreshold := flag.Int("health-threshold", 3, "Failed probes in a row before a storage node is marked down")
+	tlsFiles := certs.Flags()
+	adminTokens := flag.String("admin-tokens", "", "File of admin bearer tokens, one \"name role token\" per line, where role is viewer or operator")
+	adminOperators := flag.String("admin-operators", "", "Comma-separated client certificate common names allowed to change the cluster")
+	adminViewers := flag.String("admin-viewers", "", "Comma-separated client certificate common names allowed to view the cluster, including web servers")
+	adminAnonymousRole := flag.String("admin-anonymous-role", "none", "Role of admin callers with neither a token nor a listed certificate: none, viewer or operator")
+	adminAuditLog := flag.String("admin-audit-log", "", "File to append a record of every admin call to (default standard error)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "How long to let in-flight admin requests finish after SIGINT or SIGTERM")
+	logOptions := logging.Flags()
+	traceOptions := tracing.Flags()
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics on at /metrics, e.g. localhost:9090 (disabled if empty)")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	err := logOptions.Setup()
+	if err != nil {
+		slog.Error("Failed to set up logging", "err", err)
+		os.Exit(1)
+	}
+	shutdownTracing, err := traceOptions.Setup("controller")
+	if err != nil {
+		slog.Error("Failed to set up tracing", "err", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	if flag.NArg() != 2 {
+		fmt.Println("Error: Incorrect number of arguments")
+		printUsage()
+		return
+	}
+	adminAddress := flag.Arg(0)
+	storageServers := strings.Split(flag.Arg(1), ",")
+
+	clientCreds, err := tlsFiles.ClientCredentials()
+	if err != nil {
+		slog.Error("Failed to load TLS certificates", "err", err)
+		os.Exit(1)
+	}
+	adminCreds, err := tlsFiles.ServerCredentials()
+	if err != nil {
+		slog.Error("Failed to load TLS certificates", "err", err)
+		os.Exit(1)
+	}
+	adminAccess, err := web.LoadAdminAccess(*adminTokens, *adminOperators, *adminViewers, *adminAnonymousRole)
+	if err != nil {
+		slog.Error("Failed to load admin tokens", "err", err)
+		os.Exit(1)
+	}
+
+	var auditLog *slog.Logger
+	if *adminAuditLog != "" {
+		file, err := os.OpenFile(*adminAuditLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			slog.Error("Failed to open admin audit log", "err", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		auditLog, err = logOptions.NewLogger(file, slog.LevelInfo)
+		if err != nil {
+			slog.Error("Failed to open admin audit log", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("Starting controller...")
+	fmt.Printf("Admin Address: %s\n", adminAddress)
+	fmt.Printf("Storage Servers: %s\n", strings.Join(storageServers, ", "))
+
+	controller := &web.NetworkVideoContentService{
+		AdminServer:                 adminAddress,
+		StorageServers:              storageServers,
+		MigrationParallelism:        *migrationParallelism,
+		MigrationBytesPerSecond:     *migrationRate,
+		HealthCheckInterval:         *healthInterval,
+		HealthCheckFailureThreshold: *healthThreshold,
+		ClientCredentials:           clientCreds,
+		AdminCredentials:            adminCreds,
+		AdminAccess:                 adminAccess,
+		AdminAuditLog:               auditLog,
+	}
+	err = controller.Start()
+	if err != nil {
+		slog.Error("Failed to start admin server", "err", err)
+		os.Exit(1)
+	}
+	if *metricsAddr != "" {
+		if err := metrics.Serve(*metricsAddr); err != nil {
+			slog.Error("Failed to serve metrics", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	// Run until SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	// A second signal kills the controller straight away
+	stop()
+
+	fmt.Println("Shutting down controller...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := controller.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error while shutting down", "err", err)
+	}
+}
@@ -1,27 +1,72 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
+	"tritontube/internal/certs"
+	"tritontube/internal/logging"
+	"tritontube/internal/metrics"
 	pb "tritontube/internal/proto"
 	"tritontube/internal/storage"
+	"tritontube/internal/tracing"
 )
 
 func main() {
 	host := flag.String("host", "localhost", "Host address for the server")
 	port := flag.Int("port", 8090, "Port number for the server")
+	scrubInterval := flag.Duration("scrub-interval", 24*time.Hour, "How often to verify stored files against their checksums (0 to disable)")
+	scrubRate := flag.Int64("scrub-rate", 10<<20, "Maximum bytes per second the scrubber reads (0 for unlimited)")
+	quarantineDir := flag.String("quarantine-dir", "", "Directory for corrupt files found by the scrubber (default <baseDir>/.quarantine)")
+	backendName := flag.String("backend", "fs", "Where to keep content: fs (files under baseDir), bolt (a single database file in baseDir) or s3")
+	s3Endpoint := flag.String("s3-endpoint", "", "Host and port of the S3-compatible object store for the s3 backend")
+	s3Bucket := flag.String("s3-bucket", "tritontube", "Bucket for the s3 backend")
+	s3Region := flag.String("s3-region", "", "Region for the s3 backend")
+	s3Secure := flag.Bool("s3-secure", true, "Connect to the object store over HTTPS")
+	quota := flag.Int64("quota", 0, "Maximum bytes of content to store (0 for no quota)")
+	tlsFiles := certs.Flags()
+	transferPeers := flag.String("transfer-peers", "", "Comma-separated addresses of the storage nodes files may be moved to without TLS, as the controller names them, or * for any")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "How long to let in-flight requests finish after SIGINT or SIGTERM")
+	logOptions := logging.Flags()
+	traceOptions := tracing.Flags()
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics on at /metrics, e.g. localhost:9090 (disabled if empty)")
 	flag.Parse()
 
+	err := logOptions.Setup()
+	if err != nil {
+		slog.Error("Failed to set up logging", "err", err)
+		os.Exit(1)
+	}
+	shutdownTracing, err := traceOptions.Setup("storage")
+	if err != nil {
+		slog.Error("Failed to set up tracing", "err", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Validate arguments
 	if *port <= 0 {
 		panic("Error: Port number must be positive")
 	}
+	if *quota < 0 {
+		fmt.Println("Error: Quota must not be negative")
+		os.Exit(2)
+	}
 
 	if flag.NArg() < 1 {
 		fmt.Println("Usage: storage [OPTIONS] <baseDir>")
@@ -34,16 +79,133 @@ func main() {
 	fmt.Printf("Host: %s\n", *host)
 	fmt.Printf("Port: %d\n", *port)
 	fmt.Printf("Base Directory: %s\n", baseDir)
+	fmt.Printf("Backend: %s\n", *backendName)
+
+	var backend storage.Backend
+	switch *backendName {
+	case "fs":
+		backend = storage.FSBackend{Dir: baseDir}
+	case "bolt":
+		boltBackend, err := storage.OpenBoltBackend(filepath.Join(baseDir, "content.db"))
+		if err != nil {
+			slog.Error("Failed to open database", "err", err)
+			os.Exit(1)
+		}
+		backend = boltBackend
+	case "s3":
+		// Credentials come from the environment so they stay out of ps
+		s3Backend, err := storage.OpenS3Backend(storage.S3Options{
+			Endpoint: *s3Endpoint,
+			Bucket: *s3Bucket,
+			AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			Region: *s3Region,
+			Secure: *s3Secure,
+		})
+		if err != nil {
+			slog.Error("Failed to connect to object store", "err", err)
+			os.Exit(1)
+		}
+		backend = s3Backend
+	default:
+		fmt.Printf("Error: unknown backend %q\n", *backendName)
+		return
+	}
+	defer backend.Close()
+
+	serverCreds, err := tlsFiles.ServerCredentials()
+	if err != nil {
+		slog.Error("Failed to load TLS certificates", "err", err)
+		os.Exit(1)
+	}
+	clientCreds, err := tlsFiles.ClientCredentials()
+	if err != nil {
+		slog.Error("Failed to load TLS certificates", "err", err)
+		os.Exit(1)
+	}
 
 	lis, err := net.Listen("tcp", *host + ":" + strconv.Itoa(*port))
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		slog.Error("Failed to listen", "err", err)
+		os.Exit(1)
+	}
+
+	contentServer := &storage.NetworkVideoContentServer{
+		Dir: baseDir,
+		Backend: backend,
+		StartedAt: time.Now(),
+		QuarantineDir: *quarantineDir,
+		ScrubBytesPerSecond: *scrubRate,
+		QuotaBytes: *quota,
+		ClientCredentials: clientCreds,
+		TLSEnabled: tlsFiles.Enabled(),
+	}
+	if *transferPeers != "" {
+		contentServer.TransferPeers = strings.Split(*transferPeers, ",")
+	}
+	if err := contentServer.CleanTempFiles(); err != nil {
+		slog.Error("Failed to clean up temporary files", "err", err)
+		os.Exit(1)
+	}
+
+	if *metricsAddr != "" {
+		prometheus.MustRegister(contentServer.MetricsCollector())
+		if err := metrics.Serve(*metricsAddr); err != nil {
+			slog.Error("Failed to serve metrics", "err", err)
+			os.Exit(1)
+		}
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterNetworkVideoContentServer(s, &storage.NetworkVideoContentServer{Dir: baseDir})
-	
+	// Stop on SIGINT or SIGTERM, once in-flight requests have finished
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scrubberDone := make(chan struct{})
+	go func() {
+		defer close(scrubberDone)
+		if *scrubInterval > 0 {
+			contentServer.RunScrubber(ctx, *scrubInterval)
+		}
+	}()
+
+	s := grpc.NewServer(
+		grpc.Creds(serverCreds),
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor, logging.UnaryServerInterceptor),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor, logging.StreamServerInterceptor),
+		tracing.ServerOption(),
+	)
+	pb.RegisterNetworkVideoContentServer(s, contentServer)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		// A second signal kills the server straight away
+		stop()
+
+		fmt.Println("Shutting down storage server...")
+		healthServer.Shutdown()
+		graceful := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(graceful)
+		}()
+		select {
+		case <-graceful:
+		case <-time.After(*shutdownTimeout):
+			slog.Warn("Requests still running, stopping anyway", "timeout", *shutdownTimeout)
+			s.Stop()
+		}
+	}()
+
 	if err := s.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+		slog.Error("Failed to serve", "err", err)
+		os.Exit(1)
 	}
+
+	// Let the scrubber and in-flight writes finish before closing the backend
+	<-stopped
+	<-scrubberDone
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"tritontube/internal/certs"
+)
+
+func printUsage() {
+	fmt.Println("Usage: tritontube <command> [OPTIONS]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  certs [OPTIONS] [NAME...]   Generate a local CA and certificates for mutual TLS (for testing)")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "certs":
+		generateCerts(os.Args[2:])
+	default:
+		fmt.Printf("Unknown command: %s\n", os.Args[1])
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+// generateCerts creates a CA in the output directory, unless there is one
+// already, and signs a certificate for each name with it.
+func generateCerts(args []string) {
+	flags := flag.NewFlagSet("certs", flag.ExitOnError)
+	out := flags.String("out", "certs", "Directory to write certificates and keys to")
+	hosts := flags.String("hosts", "localhost,127.0.0.1", "Comma-separated host names and IP addresses the certificates are valid for")
+	validFor := flags.Duration("valid-for", 365*24*time.Hour, "How long the certificates are valid for")
+	flags.Usage = func() {
+		fmt.Println("Usage: tritontube certs [OPTIONS] [NAME...]")
+		fmt.Println()
+		fmt.Println("Writes ca.pem and ca-key.pem, then NAME.pem and NAME-key.pem for each name")
+		fmt.Println("(web, storage and admin by default). An existing CA in the output directory")
+		fmt.Println("is reused, so more names can be added later.")
+		fmt.Println()
+		fmt.Println("Options:")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	names := flags.Args()
+	if len(names) == 0 {
+		names = []string{"web", "storage", "admin"}
+	}
+
+	err := os.MkdirAll(*out, 0755)
+	if err != nil {
+		fmt.Println("Error creating output directory:", err)
+		os.Exit(1)
+	}
+
+	ca, err := certs.LoadOrCreateCA(*out, *validFor)
+	if err != nil {
+		fmt.Println("Error loading CA:", err)
+		os.Exit(1)
+	}
+
+	for _, name := range names {
+		err := ca.Issue(*out, name, strings.Split(*hosts, ","), *validFor)
+		if err != nil {
+			fmt.Printf("Error issuing certificate for %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s.pem and %s-key.pem\n", name, name)
+	}
+	fmt.Printf("Certificates are in %s, signed by ca.pem\n", *out)
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tritontube/internal/web"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config describes a web server. It is read from a YAML file given with
+// -config, or built from the positional arguments. Either way, environment
+// variables named after a key override it, e.g. TRITONTUBE_CONTENT_NODES for
+// content.nodes, and flags given on the command line override both.
+type config struct {
+	Host            string        `yaml:"host" flag:"host"`
+	Port            int           `yaml:"port" flag:"port"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" flag:"shutdown-timeout"`
+	// MetricsAddr is where /metrics is served, apart from the website so
+	// that it is not exposed to viewers. Empty disables it.
+	MetricsAddr string `yaml:"metrics_addr" flag:"metrics-addr"`
+
+	Metadata struct {
+		// Type is sqlite.
+		Type string `yaml:"type"`
+		Path string `yaml:"path"`
+		// CacheTTL is how long reads and lists are cached, and
+		// NegativeCacheTTL how long reads of missing videos are. Zero
+		// disables either.
+		CacheTTL         time.Duration `yaml:"cache_ttl" flag:"metadata-cache-ttl"`
+		NegativeCacheTTL time.Duration `yaml:"negative_cache_ttl" flag:"metadata-negative-cache-ttl"`
+	} `yaml:"metadata"`
+
+	Content struct {
+		// Type is fs, nw or controller.
+		Type string `yaml:"type"`
+		// Dir holds the content of the fs type.
+		Dir string `yaml:"dir"`
+		// AdminListen is where the nw type serves the admin API, and Nodes
+		// are its storage servers.
+		AdminListen string   `yaml:"admin_listen"`
+		Nodes       []string `yaml:"nodes"`
+		// Controller is the controller the controller type follows, and
+		// ControllerToken the bearer token sent to it. The token has no flag,
+		// so that it stays out of ps.
+		Controller      string `yaml:"controller"`
+		ControllerToken string `yaml:"controller_token"`
+		// CacheBytes bounds the content kept in memory, 0 to disable the
+		// cache.
+		CacheBytes int64 `yaml:"cache_bytes" flag:"content-cache-bytes"`
+
+		Migration struct {
+			Parallelism    int   `yaml:"parallelism" flag:"migration-parallelism"`
+			BytesPerSecond int64 `yaml:"bytes_per_second" flag:"migration-rate"`
+		} `yaml:"migration"`
+		Health struct {
+			Interval         time.Duration `yaml:"interval" flag:"health-interval"`
+			FailureThreshold int           `yaml:"failure_threshold" flag:"health-threshold"`
+		} `yaml:"health"`
+	} `yaml:"content"`
+
+	Admin struct {
+		TokensFile string   `yaml:"tokens_file" flag:"admin-tokens"`
+		Operators  []string `yaml:"operators" flag:"admin-operators"`
+		Viewers    []string `yaml:"viewers" flag:"admin-viewers"`
+		// AnonymousRole is the role of callers the others do not cover.
+		AnonymousRole string `yaml:"anonymous_role" flag:"admin-anonymous-role"`
+		AuditLog      string `yaml:"audit_log" flag:"admin-audit-log"`
+	} `yaml:"admin"`
+
+	Transcoding struct {
+		AudioBitrate string          `yaml:"audio_bitrate"`
+		Profiles     []profileConfig `yaml:"profiles"`
+	} `yaml:"transcoding"`
+
+	Uploads struct {
+		MaxBytes int64 `yaml:"max_bytes"`
+	} `yaml:"uploads"`
+
+	TLS struct {
+		Cert string `yaml:"cert" flag:"tls-cert"`
+		Key  string `yaml:"key" flag:"tls-key"`
+		CA   string `yaml:"ca" flag:"tls-ca"`
+	} `yaml:"tls"`
+}
+
+type profileConfig struct {
+	Name         string `yaml:"name"`
+	Height       int    `yaml:"height"`
+	VideoBitrate string `yaml:"video_bitrate"`
+}
+
+// envPrefix starts the name of every environment variable overriding a key.
+const envPrefix = "TRITONTUBE_"
+
+// defaultConfig is the configuration before any file, environment variable or
+// flag is applied, taken from the flag defaults.
+func defaultConfig() *config {
+	cfg := &config{}
+	cfg.Transcoding.AudioBitrate = web.DefaultAudioBitrate
+	for _, profile := range web.DefaultTranscodeProfiles {
+		cfg.Transcoding.Profiles = append(cfg.Transcoding.Profiles, profileConfig(profile))
+	}
+
+	walkConfig(cfg, func(key string, field reflect.StructField, value reflect.Value) error {
+		if f := flag.Lookup(field.Tag.Get("flag")); f != nil {
+			return setValue(value, f.DefValue)
+		}
+		return nil
+	})
+	return cfg
+}
+
+// loadFile reads a YAML config file over cfg. Keys the file does not set keep
+// their current values. Unknown keys and values of the wrong type are
+// reported with their line and key.
+func (cfg *config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var root yaml.Node
+	err = yaml.Unmarshal(data, &root)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(root.Content) == 0 {
+		// Empty file
+		return nil
+	}
+
+	errs := checkNode(root.Content[0], reflect.TypeOf(*cfg), "")
+	if len(errs) > 0 {
+		return fmt.Errorf("%s:\n%w", path, errors.Join(errs...))
+	}
+	return root.Content[0].Decode(cfg)
+}
+
+// checkNode checks that node can be decoded into a value of type t, returning
+// an error naming the key for every part that cannot.
+func checkNode(node *yaml.Node, t reflect.Type, key string) []error {
+	switch {
+	case t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Duration(0)):
+		if node.Kind != yaml.MappingNode {
+			return []error{fmt.Errorf("line %d: %s: must be a mapping", node.Line, keyName(key))}
+		}
+		var errs []error
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			field, ok := fieldByTag(t, name)
+			if !ok {
+				errs = append(errs, fmt.Errorf("line %d: %s: unknown key", node.Content[i].Line, joinKey(key, name)))
+				continue
+			}
+			errs = append(errs, checkNode(node.Content[i+1], field.Type, joinKey(key, name))...)
+		}
+		return errs
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		if node.Kind != yaml.SequenceNode {
+			return []error{fmt.Errorf("line %d: %s: must be a list", node.Line, key)}
+		}
+		var errs []error
+		for i, item := range node.Content {
+			errs = append(errs, checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", key, i))...)
+		}
+		return errs
+	default:
+		err := node.Decode(reflect.New(t).Interface())
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return []error{fmt.Errorf("line %d: %s: expected %s, not %q", node.Line, key, typeName(t), node.Value)}
+		} else if err != nil {
+			return []error{fmt.Errorf("line %d: %s: %w", node.Line, key, err)}
+		}
+		return nil
+	}
+}
+
+// fieldByTag finds the field of struct type t with the given YAML key.
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		if t.Field(i).Tag.Get("yaml") == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinKey(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func keyName(key string) string {
+	if key == "" {
+		return "top level"
+	}
+	return key
+}
+
+// typeName describes a type in the terms of the config file.
+func typeName(t reflect.Type) string {
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		return "a duration such as 5s"
+	case t.Kind() == reflect.Int || t.Kind() == reflect.Int64:
+		return "a whole number"
+	case t.Kind() == reflect.Slice:
+		return "a list of strings"
+	default:
+		return "a string"
+	}
+}
+
+// fromArgs fills cfg from the positional arguments METADATA_TYPE
+// METADATA_OPTIONS CONTENT_TYPE CONTENT_OPTIONS.
+func (cfg *config) fromArgs(args []string) {
+	cfg.Metadata.Type = args[0]
+	cfg.Metadata.Path = args[1]
+	cfg.Content.Type = args[2]
+	switch args[2] {
+	case "fs":
+		cfg.Content.Dir = args[3]
+	case "nw":
+		// The admin address comes first, then the storage servers
+		addresses := strings.Split(args[3], ",")
+		cfg.Content.AdminListen = addresses[0]
+		cfg.Content.Nodes = addresses[1:]
+	case "controller":
+		cfg.Content.Controller = args[3]
+	}
+}
+
+// applyEnv overrides cfg with the environment variables named after its keys.
+func (cfg *config) applyEnv() error {
+	return walkConfig(cfg, func(key string, field reflect.StructField, value reflect.Value) error {
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		text, ok := os.LookupEnv(name)
+		if !ok {
+			return nil
+		}
+		if err := setValue(value, text); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// applyFlags overrides cfg with the flags given on the command line.
+func (cfg *config) applyFlags() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	return walkConfig(cfg, func(key string, field reflect.StructField, value reflect.Value) error {
+		name := field.Tag.Get("flag")
+		if !set[name] {
+			return nil
+		}
+		if err := setValue(value, flag.Lookup(name).Value.String()); err != nil {
+			return fmt.Errorf("-%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// walkConfig calls fn for every key in cfg that holds a single value or a
+// list of strings, along with its dotted name, e.g. content.nodes.
+func walkConfig(cfg *config, fn func(key string, field reflect.StructField, value reflect.Value) error) error {
+	var walk func(prefix string, value reflect.Value) error
+	walk = func(prefix string, value reflect.Value) error {
+		for i := range value.NumField() {
+			field := value.Type().Field(i)
+			key := prefix + field.Tag.Get("yaml")
+			fieldValue := value.Field(i)
+
+			var err error
+			switch {
+			case fieldValue.Kind() == reflect.Struct:
+				err = walk(key+".", fieldValue)
+			case fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() != reflect.String:
+				// Lists of tables are only set in the file
+			default:
+				err = fn(key, field, fieldValue)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk("", reflect.ValueOf(cfg).Elem())
+}
+
+// setValue parses text into value. Lists are comma-separated.
+func setValue(value reflect.Value, text string) error {
+	switch value.Interface().(type) {
+	case string:
+		value.SetString(text)
+	case []string:
+		var list []string
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		value.Set(reflect.ValueOf(list))
+	case time.Duration:
+		duration, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", text)
+		}
+		value.SetInt(int64(duration))
+	case int, int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", text)
+		}
+		value.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+var bitratePattern = regexp.MustCompile(`^[1-9][0-9]*[kM]?$`)
+
+// validate checks cfg, naming the key at fault in every error.
+func (cfg *config) validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		invalid("port", "must be between 1 and 65535, not %d", cfg.Port)
+	}
+	if cfg.ShutdownTimeout < 0 {
+		invalid("shutdown_timeout", "must not be negative")
+	}
+
+	switch cfg.Metadata.Type {
+	case "sqlite":
+		if cfg.Metadata.Path == "" {
+			invalid("metadata.path", "is required for the sqlite type")
+		}
+	case "":
+		invalid("metadata.type", "is required")
+	default:
+		invalid("metadata.type", "unsupported type %q, must be sqlite", cfg.Metadata.Type)
+	}
+
+	if cfg.Metadata.CacheTTL < 0 {
+		invalid("metadata.cache_ttl", "must not be negative")
+	}
+	if cfg.Metadata.NegativeCacheTTL < 0 {
+		invalid("metadata.negative_cache_ttl", "must not be negative")
+	}
+
+	switch cfg.Content.Type {
+	case "fs":
+		if cfg.Content.Dir == "" {
+			invalid("content.dir", "is required for the fs type")
+		}
+	case "nw":
+		if cfg.Content.AdminListen == "" {
+			invalid("content.admin_listen", "is required for the nw type")
+		} else if _, _, err := net.SplitHostPort(cfg.Content.AdminListen); err != nil {
+			invalid("content.admin_listen", "%v", err)
+		}
+		for i, node := range cfg.Content.Nodes {
+			if _, _, err := net.SplitHostPort(node); err != nil {
+				invalid(fmt.Sprintf("content.nodes[%d]", i), "%v", err)
+			}
+		}
+	case "controller":
+		if cfg.Content.Controller == "" {
+			invalid("content.controller", "is required for the controller type")
+		}
+	case "":
+		invalid("content.type", "is required")
+	default:
+		invalid("content.type", "unsupported type %q, must be fs, nw or controller", cfg.Content.Type)
+	}
+	if cfg.Content.CacheBytes < 0 {
+		invalid("content.cache_bytes", "must not be negative")
+	}
+	if cfg.Content.Migration.Parallelism < 1 {
+		invalid("content.migration.parallelism", "must be at least 1")
+	}
+	if cfg.Content.Migration.BytesPerSecond < 0 {
+		invalid("content.migration.bytes_per_second", "must not be negative")
+	}
+	if cfg.Content.Health.Interval <= 0 {
+		invalid("content.health.interval", "must be positive")
+	}
+	if cfg.Content.Health.FailureThreshold < 1 {
+		invalid("content.health.failure_threshold", "must be at least 1")
+	}
+
+	if !bitratePattern.MatchString(cfg.Transcoding.AudioBitrate) {
+		invalid("transcoding.audio_bitrate", "invalid bitrate %q, e.g. 128k", cfg.Transcoding.AudioBitrate)
+	}
+	if len(cfg.Transcoding.Profiles) == 0 {
+		invalid("transcoding.profiles", "at least one profile is required")
+	}
+	names := make(map[string]bool)
+	for i, profile := range cfg.Transcoding.Profiles {
+		key := fmt.Sprintf("transcoding.profiles[%d]", i)
+		if profile.Name == "" {
+			invalid(key+".name", "is required")
+		} else if names[profile.Name] {
+			invalid(key+".name", "duplicate profile %q", profile.Name)
+		}
+		names[profile.Name] = true
+		if profile.Height < 0 || profile.Height%2 != 0 {
+			invalid(key+".height", "must be a positive even number, or 0 to keep the uploaded size")
+		}
+		if !bitratePattern.MatchString(profile.VideoBitrate) {
+			invalid(key+".video_bitrate", "invalid bitrate %q, e.g. 3000k", profile.VideoBitrate)
+		}
+	}
+
+	if cfg.Uploads.MaxBytes < 0 {
+		invalid("uploads.max_bytes", "must not be negative")
+	}
+
+	if (cfg.TLS.Cert != "" || cfg.TLS.Key != "" || cfg.TLS.CA != "") && (cfg.TLS.Cert == "" || cfg.TLS.Key == "" || cfg.TLS.CA == "") {
+		invalid("tls", "cert, key and ca must be set together")
+	}
+
+	return errors.Join(errs...)
+}
+
+// transcodeProfiles converts the configured profiles for the web server.
+func (cfg *config) transcodeProfiles() []web.TranscodeProfile {
+	var profiles []web.TranscodeProfile
+	for _, profile := range cfg.Transcoding.Profiles {
+		profiles = append(profiles, web.TranscodeProfile(profile))
+	}
+	return profiles
+}
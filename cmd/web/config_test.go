@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The flags main defines that the tests below set
+var _ = flag.Int("port", 8080, "Port number for the web server")
+
+// writeConfig writes a config file, returning its path.
+func writeConfig(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// validConfig is a complete configuration that passes validate.
+func validConfig() *config {
+	cfg := defaultConfig()
+	cfg.Port = 8080
+	cfg.Metadata.Type = "sqlite"
+	cfg.Metadata.Path = "metadata.db"
+	cfg.Content.Type = "fs"
+	cfg.Content.Dir = "videos"
+	cfg.Content.Migration.Parallelism = 4
+	cfg.Content.Health.Interval = 5 * time.Second
+	cfg.Content.Health.FailureThreshold = 3
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *config)
+		want   string
+	}{
+		{"valid", func(cfg *config) {}, ""},
+		{"port out of range", func(cfg *config) { cfg.Port = 70000 }, "port: must be between 1 and 65535, not 70000"},
+		{"missing metadata type", func(cfg *config) { cfg.Metadata.Type = "" }, "metadata.type: is required"},
+		{"unknown metadata type", func(cfg *config) { cfg.Metadata.Type = "etcd" }, `metadata.type: unsupported type "etcd"`},
+		{"missing fs dir", func(cfg *config) { cfg.Content.Dir = "" }, "content.dir: is required for the fs type"},
+		{"bad admin address", func(cfg *config) {
+			cfg.Content.Type = "nw"
+			cfg.Content.AdminListen = "localhost"
+		}, "content.admin_listen: "},
+		{"bad node address", func(cfg *config) {
+			cfg.Content.Type = "nw"
+			cfg.Content.AdminListen = "localhost:8081"
+			cfg.Content.Nodes = []string{"localhost:8090", "localhost"}
+		}, "content.nodes[1]: "},
+		{"missing controller", func(cfg *config) { cfg.Content.Type = "controller" }, "content.controller: is required"},
+		{"no migration parallelism", func(cfg *config) { cfg.Content.Migration.Parallelism = 0 }, "content.migration.parallelism: must be at least 1"},
+		{"negative cache ttl", func(cfg *config) { cfg.Metadata.CacheTTL = -time.Second }, "metadata.cache_ttl: must not be negative"},
+		{"bad audio bitrate", func(cfg *config) { cfg.Transcoding.AudioBitrate = "loud" }, `transcoding.audio_bitrate: invalid bitrate "loud"`},
+		{"odd profile height", func(cfg *config) { cfg.Transcoding.Profiles[0].Height = 361 }, "transcoding.profiles[0].height: "},
+		{"duplicate profile", func(cfg *config) {
+			cfg.Transcoding.Profiles = append(cfg.Transcoding.Profiles, cfg.Transcoding.Profiles[0])
+		}, "].name: duplicate profile"},
+		{"partial tls", func(cfg *config) { cfg.TLS.Cert = "web.pem" }, "tls: cert, key and ca must be set together"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := validConfig()
+			test.change(cfg)
+			err := cfg.validate()
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("validate() = %v, want no error", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("validate() = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"unknown key", "port: 8080\ncontent:\n  nodez: [localhost:8090]\n", []string{"line 3: content.nodez: unknown key"}},
+		{"unknown top-level key", "prot: 8080\n", []string{"line 1: prot: unknown key"}},
+		{"wrong type", "port: eighty\n", []string{`line 1: port: expected a whole number, not "eighty"`}},
+		{"bad duration", "content:\n  health:\n    interval: often\n", []string{"line 3: content.health.interval: expected a duration such as 5s"}},
+		{"mapping expected", "content: fs\n", []string{"line 1: content: must be a mapping"}},
+		{"list expected", "transcoding:\n  profiles: 720p\n", []string{"line 2: transcoding.profiles: must be a list"}},
+		{"unknown profile key", "transcoding:\n  profiles:\n    - name: 720p\n      bitrate: 3000k\n", []string{"line 4: transcoding.profiles[0].bitrate: unknown key"}},
+		{"every error", "prot: 8080\nport: eighty\n", []string{"line 1: prot: unknown key", "line 2: port: expected a whole number"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := defaultConfig().loadFile(writeConfig(t, test.text))
+			if err == nil {
+				t.Fatal("loadFile succeeded")
+			}
+			for _, want := range test.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("loadFile() = %v, want an error containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	path := writeConfig(t, "port: 8000\ncontent:\n  type: nw\n  nodes: [localhost:8090]\n  health:\n    interval: 10s\n")
+
+	cfg := defaultConfig()
+	if err := cfg.loadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8000 || cfg.Content.Health.Interval != 10*time.Second {
+		t.Errorf("file set port %d and health interval %v, want 8000 and 10s", cfg.Port, cfg.Content.Health.Interval)
+	}
+
+	// The environment overrides the file
+	t.Setenv("TRITONTUBE_PORT", "8001")
+	t.Setenv("TRITONTUBE_CONTENT_NODES", "localhost:8091, localhost:8092")
+	if err := cfg.applyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8001 {
+		t.Errorf("environment set port %d, want 8001", cfg.Port)
+	}
+	if want := []string{"localhost:8091", "localhost:8092"}; !slices.Equal(cfg.Content.Nodes, want) {
+		t.Errorf("environment set nodes %v, want %v", cfg.Content.Nodes, want)
+	}
+	if cfg.Content.Health.Interval != 10*time.Second {
+		t.Errorf("environment changed the health interval to %v", cfg.Content.Health.Interval)
+	}
+
+	// Flags override the environment
+	if err := flag.Set("port", "8002"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { flag.Set("port", "8080") })
+	if err := cfg.applyFlags(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8002 {
+		t.Errorf("flag set port %d, want 8002", cfg.Port)
+	}
+
+	t.Setenv("TRITONTUBE_PORT", "eighty")
+	if err := cfg.applyEnv(); err == nil || !strings.Contains(err.Error(), "TRITONTUBE_PORT") {
+		t.Errorf("applyEnv() = %v, want an error naming TRITONTUBE_PORT", err)
+	}
+}
+
+func TestFromArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want func(cfg *config) bool
+	}{
+		{[]string{"sqlite", "metadata.db", "fs", "videos"}, func(cfg *config) bool {
+			return cfg.Metadata.Type == "sqlite" && cfg.Metadata.Path == "metadata.db" && cfg.Content.Type == "fs" && cfg.Content.Dir == "videos"
+		}},
+		{[]string{"sqlite", "metadata.db", "nw", "localhost:8081,localhost:8090,localhost:8091"}, func(cfg *config) bool {
+			return cfg.Content.Type == "nw" && cfg.Content.AdminListen == "localhost:8081" &&
+				slices.Equal(cfg.Content.Nodes, []string{"localhost:8090", "localhost:8091"})
+		}},
+		{[]string{"sqlite", "metadata.db", "controller", "localhost:8081"}, func(cfg *config) bool {
+			return cfg.Content.Type == "controller" && cfg.Content.Controller == "localhost:8081"
+		}},
+	}
+
+	for _, test := range tests {
+		cfg := defaultConfig()
+		cfg.fromArgs(test.args)
+		if !test.want(cfg) {
+			t.Errorf("fromArgs(%v) gave metadata %+v and content type %q, dir %q, admin %q, nodes %v, controller %q", test.args,
+				cfg.Metadata, cfg.Content.Type, cfg.Content.Dir, cfg.Content.AdminListen, cfg.Content.Nodes, cfg.Content.Controller)
+		}
+	}
+}
+
+func TestControllerToken(t *testing.T) {
+	path := writeConfig(t, "content:\n  type: controller\n  controller: localhost:8081\n  controller_token: from-file\n")
+
+	cfg := defaultConfig()
+	if err := cfg.loadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Content.ControllerToken != "from-file" {
+		t.Errorf("token from the file = %q", cfg.Content.ControllerToken)
+	}
+
+	t.Setenv("TRITONTUBE_CONTENT_CONTROLLER_TOKEN", "from-env")
+	if err := cfg.applyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Content.ControllerToken != "from-env" {
+		t.Errorf("token from the environment = %q", cfg.Content.ControllerToken)
+	}
+}
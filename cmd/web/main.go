@@ -1,33 +1,69 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+	"tritontube/internal/certs"
+	"tritontube/internal/logging"
+	"tritontube/internal/metrics"
+	"tritontube/internal/tracing"
 	"tritontube/internal/web"
 )
 
 // printUsage prints the usage information for the application
 func printUsage() {
 	fmt.Println("Usage: ./program [OPTIONS] METADATA_TYPE METADATA_OPTIONS CONTENT_TYPE CONTENT_OPTIONS")
+	fmt.Println("       ./program [OPTIONS] -config FILE")
 	fmt.Println()
 	fmt.Println("Arguments:")
 	fmt.Println("  METADATA_TYPE         Metadata service type (sqlite, etcd)")
 	fmt.Println("  METADATA_OPTIONS      Options for metadata service (e.g., db path)")
-	fmt.Println("  CONTENT_TYPE          Content service type (fs, nw)")
-	fmt.Println("  CONTENT_OPTIONS       Options for content service (e.g., base dir, network addresses)")
+	fmt.Println("  CONTENT_TYPE          Content service type (fs, nw, controller)")
+	fmt.Println("  CONTENT_OPTIONS       Options for content service (e.g., base dir, network addresses, controller address)")
 	fmt.Println()
 	fmt.Println("Options:")
 	flag.PrintDefaults()
 	fmt.Println()
 	fmt.Println("Example: ./program sqlite db.db fs /path/to/videos")
+	fmt.Println()
+	fmt.Println("Environment variables named after a config key override it, e.g.")
+	fmt.Println("TRITONTUBE_CONTENT_NODES for content.nodes, and flags override both.")
 }
 
 func main() {
-	// Define flags
-	port := flag.Int("port", 8080, "Port number for the web server")
-	host := flag.String("host", "localhost", "Host address for the web server")
+	// Define flags. Those also found in the config file are read from
+	// there, see config.go
+	configPath := flag.String("config", "", "YAML file configuring the server, instead of the positional arguments")
+	flag.Int("port", 8080, "Port number for the web server")
+	flag.String("host", "localhost", "Host address for the web server")
+	flag.Duration("metadata-cache-ttl", 5*time.Second, "How long to cache video metadata (0 to disable)")
+	flag.Duration("metadata-negative-cache-ttl", time.Second, "How long to remember that a video does not exist (0 to disable)")
+	flag.Int64("content-cache-bytes", 256<<20, "Maximum bytes of video content to keep in memory (0 to disable the cache)")
+	flag.Int("migration-parallelism", 4, "Number of files a storage node sends at once while rebalancing")
+	flag.Int64("migration-rate", 0, "Maximum bytes per second a storage node sends while rebalancing (0 for unlimited)")
+	flag.Duration("health-interval", 5*time.Second, "How often to probe storage nodes")
+	flag.Int("health-threshold", 3, "Failed probes in a row before a storage node is marked down")
+	certs.Flags()
+	flag.String("admin-tokens", "", "File of admin bearer tokens, one \"name role token\" per line, where role is viewer or operator")
+	flag.String("admin-operators", "", "Comma-separated client certificate common names allowed to change the cluster")
+	flag.String("admin-viewers", "", "Comma-separated client certificate common names allowed to view the cluster")
+	flag.String("admin-anonymous-role", "none", "Role of admin callers with neither a token nor a listed certificate: none, viewer or operator")
+	flag.String("admin-audit-log", "", "File to append a record of every admin call to (default standard error)")
+	flag.Duration("shutdown-timeout", 30*time.Second, "How long to let in-flight requests and uploads finish after SIGINT or SIGTERM")
+	flag.String("metrics-addr", "", "Address to serve Prometheus metrics on at /metrics, e.g. localhost:9090 (disabled if empty)")
+	logOptions := logging.Flags()
+	traceOptions := tracing.Flags()
 
 	// Set custom usage message
 	flag.Usage = printUsage
@@ -35,58 +71,160 @@ func main() {
 	// Parse flags
 	flag.Parse()
 
-	// Check if the correct number of positional arguments is provided
-	if len(flag.Args()) != 4 {
-		fmt.Println("Error: Incorrect number of arguments")
-		printUsage()
+	err := logOptions.Setup()
+	if err != nil {
+		fmt.Println("Error:", err)
 		return
 	}
+	shutdownTracing, err := traceOptions.Setup("web")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer shutdownTracing(context.Background())
 
-	// Parse positional arguments
-	metadataServiceType := flag.Arg(0)
-	metadataServiceOptions := flag.Arg(1)
-	contentServiceType := flag.Arg(2)
-	contentServiceOptions := flag.Arg(3)
-
-	// Validate port number (already an int from flag, check if positive)
-	if *port <= 0 {
-		fmt.Println("Error: Invalid port number:", *port)
+	// Read the configuration from a file or the positional arguments
+	cfg := defaultConfig()
+	if *configPath != "" {
+		if flag.NArg() != 0 {
+			fmt.Println("Error: Positional arguments cannot be used with -config")
+			printUsage()
+			return
+		}
+		if err := cfg.loadFile(*configPath); err != nil {
+			fmt.Println("Error reading config file:", err)
+			return
+		}
+	} else if flag.NArg() == 4 {
+		cfg.fromArgs(flag.Args())
+	} else {
+		fmt.Println("Error: Incorrect number of arguments")
 		printUsage()
 		return
 	}
+	if err := cfg.applyEnv(); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := cfg.applyFlags(); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := cfg.validate(); err != nil {
+		fmt.Println("Error: Invalid configuration:")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Println("  " + line)
+		}
+		return
+	}
+	tlsFiles := certs.Files{CertFile: cfg.TLS.Cert, KeyFile: cfg.TLS.Key, CAFile: cfg.TLS.CA}
+	adminAccess, err := web.LoadAdminAccess(cfg.Admin.TokensFile, strings.Join(cfg.Admin.Operators, ","), strings.Join(cfg.Admin.Viewers, ","), cfg.Admin.AnonymousRole)
+	if err != nil {
+		fmt.Println("Error loading admin tokens:", err)
+		return
+	}
 
 	// Construct metadata service
 	var metadataService web.VideoMetadataService
-	fmt.Println("Creating metadata service of type", metadataServiceType, "with options", metadataServiceOptions)
-	if metadataServiceType == "sqlite" {
-		metadataService = web.SQLiteVideoMetadataService{
-			DBPath: metadataServiceOptions,
+	fmt.Println("Creating metadata service of type", cfg.Metadata.Type, "at", cfg.Metadata.Path)
+	metadataService = web.SQLiteVideoMetadataService{
+		DBPath: cfg.Metadata.Path,
+	}
+	if cfg.Metadata.CacheTTL > 0 || cfg.Metadata.NegativeCacheTTL > 0 {
+		metadataService = &web.CachedVideoMetadataService{
+			VideoMetadataService: metadataService,
+			TTL: cfg.Metadata.CacheTTL,
+			NegativeTTL: cfg.Metadata.NegativeCacheTTL,
 		}
-	} else {
-		fmt.Println("Error: Unsupported metadata service of type", metadataServiceType)
-		return
 	}
 
 	// Construct content service
 	var contentService web.VideoContentService
-	fmt.Println("Creating content service of type", contentServiceType, "with options", contentServiceOptions)
-	if contentServiceType == "fs" {
-		contentService = web.FSVideoContentService{
-			FSDir: contentServiceOptions,
+	fmt.Println("Creating content service of type", cfg.Content.Type)
+	if cfg.Content.Type == "fs" {
+		fsContentService := web.FSVideoContentService{
+			FSDir: cfg.Content.Dir,
 		}
-	} else if contentServiceType == "nw" {
-		contentService = &web.NetworkVideoContentService{
-			AdminServer: strings.Split(contentServiceOptions, ",")[0],
-			StorageServers: strings.Split(contentServiceOptions, ",")[1:],
+		if err := fsContentService.CleanTempFiles(); err != nil {
+			fmt.Println("Error cleaning up temporary files:", err)
+			return
 		}
+		contentService = fsContentService
 	} else {
-		fmt.Println("Error: Unsupported content service of type", contentServiceType)
-		return
+		clientCreds, err := tlsFiles.ClientCredentials()
+		if err != nil {
+			fmt.Println("Error loading TLS certificates:", err)
+			return
+		}
+		nwContentService := &web.NetworkVideoContentService{
+			MigrationParallelism: cfg.Content.Migration.Parallelism,
+			MigrationBytesPerSecond: cfg.Content.Migration.BytesPerSecond,
+			HealthCheckInterval: cfg.Content.Health.Interval,
+			HealthCheckFailureThreshold: cfg.Content.Health.FailureThreshold,
+			ClientCredentials: clientCreds,
+		}
+
+		if cfg.Content.Type == "controller" {
+			// Follow the ring owned by a standalone controller
+			nwContentService.Controller = cfg.Content.Controller
+			nwContentService.ControllerToken = cfg.Content.ControllerToken
+			if nwContentService.ControllerToken == "" {
+				// The variable the admin CLI reads, for setups predating the key
+				nwContentService.ControllerToken = os.Getenv("TRITONTUBE_ADMIN_TOKEN")
+			}
+		} else {
+			// Own the ring, serving the admin API alongside the website
+			nwContentService.AdminServer = cfg.Content.AdminListen
+			nwContentService.StorageServers = cfg.Content.Nodes
+			nwContentService.AdminCredentials, err = tlsFiles.ServerCredentials()
+			if err != nil {
+				fmt.Println("Error loading TLS certificates:", err)
+				return
+			}
+			nwContentService.AdminAccess = adminAccess
+			if cfg.Admin.AuditLog != "" {
+				file, err := os.OpenFile(cfg.Admin.AuditLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+				if err != nil {
+					fmt.Println("Error opening admin audit log:", err)
+					return
+				}
+				defer file.Close()
+				nwContentService.AdminAuditLog, err = logOptions.NewLogger(file, slog.LevelInfo)
+				if err != nil {
+					fmt.Println("Error opening admin audit log:", err)
+					return
+				}
+			}
+		}
+
+		err = nwContentService.Start()
+		if err != nil {
+			fmt.Println("Error starting content service:", err)
+			return
+		}
+		contentService = nwContentService
+	}
+
+	if cfg.Content.CacheBytes > 0 {
+		contentService = &web.CachedVideoContentService{
+			VideoContentService: contentService,
+			MaxBytes: cfg.Content.CacheBytes,
+		}
 	}
 
 	// Start the server
 	server := web.NewServer(metadataService, contentService)
-	listenAddr := fmt.Sprintf("%s:%d", *host, *port)
+	server.MaxUploadBytes = cfg.Uploads.MaxBytes
+	server.TranscodeProfiles = cfg.transcodeProfiles()
+	server.AudioBitrate = cfg.Transcoding.AudioBitrate
+	server.AdminAccess = adminAccess
+	if cfg.MetricsAddr != "" {
+		if err := metrics.Serve(cfg.MetricsAddr); err != nil {
+			fmt.Println("Error serving metrics:", err)
+			return
+		}
+	}
+	listenAddr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
 	lis, err := net.Listen("tcp", listenAddr)
 	if err != nil {
 		fmt.Println("Error starting listener:", err)
@@ -94,10 +232,30 @@ func main() {
 	}
 	defer lis.Close()
 
+	// Stop on SIGINT or SIGTERM, once in-flight requests have finished
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		// A second signal kills the server straight away
+		stop()
+
+		fmt.Println("Shutting down web server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			fmt.Println("Error shutting down server:", err)
+		}
+	}()
+
 	fmt.Println("Starting web server on", listenAddr)
 	err = server.Start(lis)
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		fmt.Println("Error starting server:", err)
 		return
 	}
+	<-stopped
 }
@@ -0,0 +1,168 @@
+// Package atomicfile writes files inside an os.Root so that a crash can never
+// leave a partially written file in place: data goes to a temporary file that
+// is synced and then renamed over the target.
+package atomicfile
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// TempPrefix starts the name of every temporary file. File ids may not start
+// with a dot, so temporary files can never be mistaken for content.
+const TempPrefix = ".tmp-"
+
+// beforeRename is called by WriteFile once the temporary file is written and
+// before it is renamed into place. Tests set it to simulate a crash there.
+var beforeRename = func(tempName string) {}
+
+// WriteFile atomically replaces name inside root with data. The parent
+// directory of name must already exist.
+func WriteFile(root *os.Root, name string, data []byte, perm fs.FileMode) error {
+	dir, base := path.Split(name)
+	dir = path.Clean(dir)
+
+	// The rename below goes through the real path, so make sure the parent
+	// is a plain directory and not a symlink leading out of root
+	info, err := root.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	tempName, err := tempPath(dir, base)
+	if err != nil {
+		return err
+	}
+
+	file, err := root.OpenFile(tempName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		root.Remove(tempName)
+		return err
+	}
+
+	beforeRename(tempName)
+	err = os.Rename(filepath.Join(root.Name(), filepath.FromSlash(tempName)), filepath.Join(root.Name(), filepath.FromSlash(name)))
+	if err != nil {
+		root.Remove(tempName)
+		return err
+	}
+
+	// Make the rename itself durable
+	return SyncDir(root, dir)
+}
+
+// RemoveDir atomically removes the directory name inside root along with
+// everything in it. The directory is renamed to a temporary name first, so
+// that nobody sees it half removed; if a crash interrupts the removal,
+// CleanTemp finishes it.
+func RemoveDir(root *os.Root, name string) error {
+	dir, base := path.Split(name)
+	dir = path.Clean(dir)
+
+	// Both renamed paths must stay inside root, so refuse to follow symlinks
+	info, err := root.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", name)
+	}
+	info, err = root.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	tempName, err := tempPath(dir, base)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(filepath.Join(root.Name(), filepath.FromSlash(name)), filepath.Join(root.Name(), filepath.FromSlash(tempName)))
+	if err != nil {
+		return err
+	}
+	err = SyncDir(root, dir)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(root.Name(), filepath.FromSlash(tempName)))
+}
+
+// tempPath picks a fresh temporary name in dir for a file called base.
+func tempPath(dir string, base string) (string, error) {
+	suffix := make([]byte, 8)
+	_, err := rand.Read(suffix)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, TempPrefix+base+"-"+hex.EncodeToString(suffix)), nil
+}
+
+// SyncDir flushes a directory inside root to disk, so that entries created in
+// it survive a crash.
+func SyncDir(root *os.Root, dir string) error {
+	file, err := root.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
+
+// CleanTemp removes temporary files and directories left behind under root by
+// writes and removals that never finished, and returns how many it removed.
+func CleanTemp(root *os.Root) (int, error) {
+	var removed int
+	err := fs.WalkDir(root.FS(), ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(entry.Name(), TempPrefix) {
+			return nil
+		}
+
+		if entry.IsDir() {
+			err = os.RemoveAll(filepath.Join(root.Name(), filepath.FromSlash(name)))
+			if err != nil {
+				return err
+			}
+			removed++
+			return fs.SkipDir
+		} else if entry.Type().IsRegular() {
+			err = root.Remove(name)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+
+	return removed, err
+}
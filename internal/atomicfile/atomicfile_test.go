@@ -0,0 +1,97 @@
+package atomicfile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+// errCrash is what simulated crashes panic with.
+var errCrash = errors.New("simulated crash")
+
+// writeAndCrash writes data to name, crashing just before the rename. Nothing
+// cleans up after the crash, as nothing would after a real one.
+func writeAndCrash(t *testing.T, root *os.Root, name string, data []byte) {
+	t.Helper()
+	beforeRename = func(tempName string) { panic(errCrash) }
+	defer func() {
+		beforeRename = func(tempName string) {}
+		if r := recover(); r != errCrash {
+			t.Fatalf("write did not reach the rename: %v", r)
+		}
+	}()
+
+	WriteFile(root, name, data, 0644)
+}
+
+func openRoot(t *testing.T) *os.Root {
+	root, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { root.Close() })
+	if err := root.Mkdir("video", 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestCrashBeforeRenameKeepsOldContent(t *testing.T) {
+	root := openRoot(t)
+	if err := WriteFile(root, "video/file", []byte("old content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writeAndCrash(t, root, "video/file", []byte("new content that never lands"))
+
+	data, err := fs.ReadFile(root.FS(), "video/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old content" {
+		t.Fatalf("file holds %q after a crash, want the old content", data)
+	}
+}
+
+func TestCrashBeforeRenameLeavesNoFile(t *testing.T) {
+	root := openRoot(t)
+
+	writeAndCrash(t, root, "video/file", []byte("content that never lands"))
+
+	_, err := root.Stat("video/file")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("file exists after a crash before its first write finished: %v", err)
+	}
+}
+
+func TestCleanTempRemovesCrashedWrites(t *testing.T) {
+	root := openRoot(t)
+	if err := WriteFile(root, "video/file", []byte("old content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeAndCrash(t, root, "video/file", []byte("new content"))
+	writeAndCrash(t, root, "video/other", []byte("other content"))
+
+	removed, err := CleanTemp(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("CleanTemp removed %d files, want 2", removed)
+	}
+
+	entries, err := fs.ReadDir(root.FS(), "video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), TempPrefix) {
+			t.Errorf("temporary file %s left after CleanTemp", entry.Name())
+		}
+	}
+	if len(entries) != 1 || entries[0].Name() != "file" {
+		t.Errorf("video holds %v, want just file", entries)
+	}
+}
@@ -0,0 +1,68 @@
+// Package fileid validates the video ids and filenames that address stored
+// content. A file id has the form "<videoId>/<filename>", and both parts are
+// used as path components on disk, so they must never be able to name
+// anything outside the storage root.
+package fileid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalid is returned for video ids, filenames and file ids that are not
+// safe to use as paths.
+var ErrInvalid = errors.New("invalid file id")
+
+// maxNameLength keeps names well under the usual 255 byte limit on a path
+// component, leaving room for the prefixes and suffixes of internal files.
+const maxNameLength = 200
+
+// ValidateName checks that name can be used as a single path component. Names
+// starting with a dot are rejected so that they stay free for files the
+// services keep for themselves.
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalid)
+	}
+	if len(name) > maxNameLength {
+		return fmt.Errorf("%w: name longer than %d bytes", ErrInvalid, maxNameLength)
+	}
+	if strings.HasPrefix(name, ".") {
+		return fmt.Errorf("%w: %q starts with a dot", ErrInvalid, name)
+	}
+	for _, r := range name {
+		if r == '/' || r == '\\' || r < 0x20 || r == 0x7f {
+			return fmt.Errorf("%w: %q contains a separator or control character", ErrInvalid, name)
+		}
+	}
+	return nil
+}
+
+// Validate checks both parts of a file id.
+func Validate(videoId string, filename string) error {
+	err := ValidateName(videoId)
+	if err != nil {
+		return err
+	}
+	return ValidateName(filename)
+}
+
+// Split breaks a file id into its video id and filename, validating both.
+func Split(fileId string) (string, string, error) {
+	videoId, filename, ok := strings.Cut(fileId, "/")
+	if !ok {
+		return "", "", fmt.Errorf("%w: %q is not of the form <videoId>/<filename>", ErrInvalid, fileId)
+	}
+
+	err := Validate(videoId, filename)
+	if err != nil {
+		return "", "", err
+	}
+	return videoId, filename, nil
+}
+
+// Join builds a file id from a video id and filename.
+func Join(videoId string, filename string) string {
+	return videoId + "/" + filename
+}
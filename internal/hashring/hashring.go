@@ -0,0 +1,25 @@
+// Package hashring holds the hashing shared by the web servers, which place
+// files on the consistent hash ring, and the storage nodes, which filter their
+// files by ring position.
+package hashring
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Hash gives the position of a node address or file id on the ring.
+func Hash(key string) uint64 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// InRange reports whether hash falls in [start, end), wrapping around the end
+// of the ring when start is not below end. A range with start equal to end
+// covers the whole ring.
+func InRange(hash uint64, start uint64, end uint64) bool {
+	if start < end {
+		return start <= hash && hash < end
+	}
+	return hash >= start || hash < end
+}
@@ -0,0 +1,122 @@
+// Package logging sets up structured logging, and carries a request id from
+// the web server through to the storage nodes so that every line logged for
+// one request can be found together.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options choose how much is logged and in what format.
+type Options struct {
+	// Level is debug, info, warn or error.
+	Level string
+	// Format is text or json.
+	Format string
+}
+
+// Flags registers the -log-level and -log-format flags and returns the options
+// they set once flags are parsed.
+func Flags() *Options {
+	options := &Options{}
+	flag.StringVar(&options.Level, "log-level", "info", "Least severe level to log: debug, info, warn or error")
+	flag.StringVar(&options.Format, "log-format", "text", "Log format: text or json")
+	return options
+}
+
+// Setup makes a logger with the options the default for both log/slog and
+// the log package.
+func (o Options) Setup() error {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(o.Level))
+	if err != nil {
+		return fmt.Errorf("unknown log level %q", o.Level)
+	}
+
+	logger, err := o.NewLogger(os.Stderr, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// NewLogger makes a logger writing records of at least level, rather than the
+// chosen one, to w in the chosen format. Audit logs use it so that they match
+// the other logs without being filtered by -log-level.
+func (o Options) NewLogger(w io.Writer, level slog.Leveler) (*slog.Logger, error) {
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(o.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, handlerOptions)
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", o.Format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// contextHandler adds the request id and trace id carried by the context to
+// every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+// validRequestID limits the request ids accepted from clients to ones that
+// are safe to log.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// NewRequestID makes a random request id.
+func NewRequestID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// WithRequestID returns a context carrying a request id. Ids that are not
+// safe to log are replaced with a new one.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if !validRequestID.MatchString(id) {
+		id = NewRequestID()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id carried by a context, or "" if it has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
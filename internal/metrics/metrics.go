@@ -0,0 +1,76 @@
+// Package metrics holds the Prometheus metrics shared by every TritonTube
+// binary, and serves them for scraping.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcServerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "tritontube_grpc_server_duration_seconds",
+		Help: "Time taken to handle gRPC calls, by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcClientDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "tritontube_grpc_client_duration_seconds",
+		Help: "Time taken by gRPC calls to other nodes, by method, node and status code.",
+	}, []string{"method", "node", "code"})
+)
+
+// Handler serves every registered metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve serves /metrics on addr in the background, for binaries that have no
+// HTTP server of their own. It returns an error if addr cannot be listened on.
+func Serve(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	go func() {
+		err := http.Serve(lis, mux)
+		slog.Error("Stopped serving metrics", "err", err)
+	}()
+	return nil
+}
+
+// UnaryServerInterceptor times unary calls to a gRPC server.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	started := time.Now()
+	response, err := handler(ctx, req)
+	grpcServerDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(started).Seconds())
+	return response, err
+}
+
+// StreamServerInterceptor times streaming calls to a gRPC server, from start
+// to finish.
+func StreamServerInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	started := time.Now()
+	err := handler(srv, stream)
+	grpcServerDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(started).Seconds())
+	return err
+}
+
+// UnaryClientInterceptor times unary calls made to other nodes.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	started := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	grpcClientDuration.WithLabelValues(method, cc.Target(), status.Code(err).String()).Observe(time.Since(started).Seconds())
+	return err
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// sampleCount is how many observations a histogram has recorded.
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := observer.(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestInterceptorsTimeCalls(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer(grpc.ChainUnaryInterceptor(UnaryServerInterceptor))
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("known", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(gs, healthServer)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	for range 2 {
+		if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "known"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Check of an unknown service = %v, want NotFound", err)
+	}
+
+	method := healthpb.Health_Check_FullMethodName
+	node := lis.Addr().String()
+	tests := []struct {
+		name     string
+		observer prometheus.Observer
+		want     uint64
+	}{
+		{"server OK", grpcServerDuration.WithLabelValues(method, "OK"), 2},
+		{"server NotFound", grpcServerDuration.WithLabelValues(method, "NotFound"), 1},
+		{"client OK", grpcClientDuration.WithLabelValues(method, node, "OK"), 2},
+		{"client NotFound", grpcClientDuration.WithLabelValues(method, node, "NotFound"), 1},
+	}
+	for _, test := range tests {
+		if count := sampleCount(t, test.observer); count != test.want {
+			t.Errorf("%s: timed %d calls, want %d", test.name, count, test.want)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+
+	// The address is taken
+	if err := Serve(addr); err == nil {
+		t.Fatal("Serve on an address in use succeeded")
+	}
+
+	lis.Close()
+	if err := Serve(addr); err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("GET /metrics = %s", response.Status)
+	}
+}
@@ -21,6 +21,153 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type NodeHealth int32
+
+const (
+	NodeHealth_HEALTH_UNKNOWN NodeHealth = 0
+	NodeHealth_UP             NodeHealth = 1
+	NodeHealth_SUSPECT        NodeHealth = 2
+	NodeHealth_DOWN           NodeHealth = 3
+)
+
+// Enum value maps for NodeHealth.
+var (
+	NodeHealth_name = map[int32]string{
+		0: "HEALTH_UNKNOWN",
+		1: "UP",
+		2: "SUSPECT",
+		3: "DOWN",
+	}
+	NodeHealth_value = map[string]int32{
+		"HEALTH_UNKNOWN": 0,
+		"UP":             1,
+		"SUSPECT":        2,
+		"DOWN":           3,
+	}
+)
+
+func (x NodeHealth) Enum() *NodeHealth {
+	p := new(NodeHealth)
+	*p = x
+	return p
+}
+
+func (x NodeHealth) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (NodeHealth) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_admin_proto_enumTypes[0].Descriptor()
+}
+
+func (NodeHealth) Type() protoreflect.EnumType {
+	return &file_proto_admin_proto_enumTypes[0]
+}
+
+func (x NodeHealth) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use NodeHealth.Descriptor instead.
+func (NodeHealth) EnumDescriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{0}
+}
+
+type TopologyOperation int32
+
+const (
+	TopologyOperation_ADD    TopologyOperation = 0
+	TopologyOperation_REMOVE TopologyOperation = 1
+)
+
+// Enum value maps for TopologyOperation.
+var (
+	TopologyOperation_name = map[int32]string{
+		0: "ADD",
+		1: "REMOVE",
+	}
+	TopologyOperation_value = map[string]int32{
+		"ADD":    0,
+		"REMOVE": 1,
+	}
+)
+
+func (x TopologyOperation) Enum() *TopologyOperation {
+	p := new(TopologyOperation)
+	*p = x
+	return p
+}
+
+func (x TopologyOperation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TopologyOperation) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_admin_proto_enumTypes[1].Descriptor()
+}
+
+func (TopologyOperation) Type() protoreflect.EnumType {
+	return &file_proto_admin_proto_enumTypes[1]
+}
+
+func (x TopologyOperation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TopologyOperation.Descriptor instead.
+func (TopologyOperation) EnumDescriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{1}
+}
+
+type DrainState int32
+
+const (
+	DrainState_DRAINING     DrainState = 0
+	DrainState_DRAINED      DrainState = 1
+	DrainState_DRAIN_FAILED DrainState = 2
+)
+
+// Enum value maps for DrainState.
+var (
+	DrainState_name = map[int32]string{
+		0: "DRAINING",
+		1: "DRAINED",
+		2: "DRAIN_FAILED",
+	}
+	DrainState_value = map[string]int32{
+		"DRAINING":     0,
+		"DRAINED":      1,
+		"DRAIN_FAILED": 2,
+	}
+)
+
+func (x DrainState) Enum() *DrainState {
+	p := new(DrainState)
+	*p = x
+	return p
+}
+
+func (x DrainState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DrainState) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_admin_proto_enumTypes[2].Descriptor()
+}
+
+func (DrainState) Type() protoreflect.EnumType {
+	return &file_proto_admin_proto_enumTypes[2]
+}
+
+func (x DrainState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DrainState.Descriptor instead.
+func (DrainState) EnumDescriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{2}
+}
+
 type AddNodeRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	NodeAddress   string                 `protobuf:"bytes,1,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
@@ -233,16 +380,173 @@ func (*ListNodesRequest) Descriptor() ([]byte, []int) {
 	return file_proto_admin_proto_rawDescGZIP(), []int{4}
 }
 
+type NodeInfo struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	NodeAddress         string                 `protobuf:"bytes,1,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	Health              NodeHealth             `protobuf:"varint,2,opt,name=health,proto3,enum=tritontube.NodeHealth" json:"health,omitempty"`
+	ConsecutiveFailures int32                  `protobuf:"varint,3,opt,name=consecutive_failures,json=consecutiveFailures,proto3" json:"consecutive_failures,omitempty"`
+	LastError           string                 `protobuf:"bytes,4,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	RingPosition        uint64                 `protobuf:"varint,5,opt,name=ring_position,json=ringPosition,proto3" json:"ring_position,omitempty"`
+	KeySpaceShare       float64                `protobuf:"fixed64,6,opt,name=key_space_share,json=keySpaceShare,proto3" json:"key_space_share,omitempty"`
+	Draining            bool                   `protobuf:"varint,7,opt,name=draining,proto3" json:"draining,omitempty"`
+	FileCount           int64                  `protobuf:"varint,8,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
+	BytesUsed           int64                  `protobuf:"varint,9,opt,name=bytes_used,json=bytesUsed,proto3" json:"bytes_used,omitempty"`
+	BytesFree           int64                  `protobuf:"varint,10,opt,name=bytes_free,json=bytesFree,proto3" json:"bytes_free,omitempty"`
+	UptimeSeconds       int64                  `protobuf:"varint,11,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	Version             string                 `protobuf:"bytes,12,opt,name=version,proto3" json:"version,omitempty"`
+	StatusError         string                 `protobuf:"bytes,13,opt,name=status_error,json=statusError,proto3" json:"status_error,omitempty"`
+	QuotaBytes          int64                  `protobuf:"varint,14,opt,name=quota_bytes,json=quotaBytes,proto3" json:"quota_bytes,omitempty"`
+	Full                bool                   `protobuf:"varint,15,opt,name=full,proto3" json:"full,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *NodeInfo) Reset() {
+	*x = NodeInfo{}
+	mi := &file_proto_admin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeInfo) ProtoMessage() {}
+
+func (x *NodeInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeInfo.ProtoReflect.Descriptor instead.
+func (*NodeInfo) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NodeInfo) GetNodeAddress() string {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetHealth() NodeHealth {
+	if x != nil {
+		return x.Health
+	}
+	return NodeHealth_HEALTH_UNKNOWN
+}
+
+func (x *NodeInfo) GetConsecutiveFailures() int32 {
+	if x != nil {
+		return x.ConsecutiveFailures
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetRingPosition() uint64 {
+	if x != nil {
+		return x.RingPosition
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetKeySpaceShare() float64 {
+	if x != nil {
+		return x.KeySpaceShare
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+func (x *NodeInfo) GetFileCount() int64 {
+	if x != nil {
+		return x.FileCount
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetBytesUsed() int64 {
+	if x != nil {
+		return x.BytesUsed
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetBytesFree() int64 {
+	if x != nil {
+		return x.BytesFree
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetStatusError() string {
+	if x != nil {
+		return x.StatusError
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetQuotaBytes() int64 {
+	if x != nil {
+		return x.QuotaBytes
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetFull() bool {
+	if x != nil {
+		return x.Full
+	}
+	return false
+}
+
 type ListNodesResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Nodes         []string               `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	NodeInfos     []*NodeInfo            `protobuf:"bytes,2,rep,name=node_infos,json=nodeInfos,proto3" json:"node_infos,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListNodesResponse) Reset() {
 	*x = ListNodesResponse{}
-	mi := &file_proto_admin_proto_msgTypes[5]
+	mi := &file_proto_admin_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -254,7 +558,7 @@ func (x *ListNodesResponse) String() string {
 func (*ListNodesResponse) ProtoMessage() {}
 
 func (x *ListNodesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_admin_proto_msgTypes[5]
+	mi := &file_proto_admin_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -267,7 +571,7 @@ func (x *ListNodesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListNodesResponse.ProtoReflect.Descriptor instead.
 func (*ListNodesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_admin_proto_rawDescGZIP(), []int{5}
+	return file_proto_admin_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ListNodesResponse) GetNodes() []string {
@@ -277,28 +581,754 @@ func (x *ListNodesResponse) GetNodes() []string {
 	return nil
 }
 
-var File_proto_admin_proto protoreflect.FileDescriptor
+func (x *ListNodesResponse) GetNodeInfos() []*NodeInfo {
+	if x != nil {
+		return x.NodeInfos
+	}
+	return nil
+}
 
-const file_proto_admin_proto_rawDesc = "" +
-	"\n" +
-	"\x11proto/admin.proto\x12\n" +
-	"tritontube\"3\n" +
-	"\x0eAddNodeRequest\x12!\n" +
-	"\fnode_address\x18\x01 \x01(\tR\vnodeAddress\"A\n" +
-	"\x0fAddNodeResponse\x12.\n" +
-	"\x13migrated_file_count\x18\x01 \x01(\x05R\x11migratedFileCount\"6\n" +
-	"\x11RemoveNodeRequest\x12!\n" +
-	"\fnode_address\x18\x01 \x01(\tR\vnodeAddress\"D\n" +
-	"\x12RemoveNodeResponse\x12.\n" +
-	"\x13migrated_file_count\x18\x01 \x01(\x05R\x11migratedFileCount\"\x12\n" +
-	"\x10ListNodesRequest\")\n" +
-	"\x11ListNodesResponse\x12\x14\n" +
-	"\x05nodes\x18\x01 \x03(\tR\x05nodes2\xf5\x01\n" +
+// PlanTopologyChange computes what an AddNode or RemoveNode would migrate
+// without moving anything.
+type PlanTopologyChangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operation     TopologyOperation      `protobuf:"varint,1,opt,name=operation,proto3,enum=tritontube.TopologyOperation" json:"operation,omitempty"`
+	NodeAddress   string                 `protobuf:"bytes,2,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlanTopologyChangeRequest) Reset() {
+	*x = PlanTopologyChangeRequest{}
+	mi := &file_proto_admin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlanTopologyChangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlanTopologyChangeRequest) ProtoMessage() {}
+
+func (x *PlanTopologyChangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlanTopologyChangeRequest.ProtoReflect.Descriptor instead.
+func (*PlanTopologyChangeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PlanTopologyChangeRequest) GetOperation() TopologyOperation {
+	if x != nil {
+		return x.Operation
+	}
+	return TopologyOperation_ADD
+}
+
+func (x *PlanTopologyChangeRequest) GetNodeAddress() string {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return ""
+}
+
+type PlannedTransfer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Destination   string                 `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	FileCount     int32                  `protobuf:"varint,3,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
+	ByteCount     int64                  `protobuf:"varint,4,opt,name=byte_count,json=byteCount,proto3" json:"byte_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlannedTransfer) Reset() {
+	*x = PlannedTransfer{}
+	mi := &file_proto_admin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlannedTransfer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlannedTransfer) ProtoMessage() {}
+
+func (x *PlannedTransfer) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlannedTransfer.ProtoReflect.Descriptor instead.
+func (*PlannedTransfer) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PlannedTransfer) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *PlannedTransfer) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *PlannedTransfer) GetFileCount() int32 {
+	if x != nil {
+		return x.FileCount
+	}
+	return 0
+}
+
+func (x *PlannedTransfer) GetByteCount() int64 {
+	if x != nil {
+		return x.ByteCount
+	}
+	return 0
+}
+
+type NodeLoad struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeAddress   string                 `protobuf:"bytes,1,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	FileCount     int32                  `protobuf:"varint,2,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
+	ByteCount     int64                  `protobuf:"varint,3,opt,name=byte_count,json=byteCount,proto3" json:"byte_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeLoad) Reset() {
+	*x = NodeLoad{}
+	mi := &file_proto_admin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeLoad) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeLoad) ProtoMessage() {}
+
+func (x *NodeLoad) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeLoad.ProtoReflect.Descriptor instead.
+func (*NodeLoad) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *NodeLoad) GetNodeAddress() string {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return ""
+}
+
+func (x *NodeLoad) GetFileCount() int32 {
+	if x != nil {
+		return x.FileCount
+	}
+	return 0
+}
+
+func (x *NodeLoad) GetByteCount() int64 {
+	if x != nil {
+		return x.ByteCount
+	}
+	return 0
+}
+
+type PlanTopologyChangeResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Transfers      []*PlannedTransfer     `protobuf:"bytes,1,rep,name=transfers,proto3" json:"transfers,omitempty"`
+	TotalFileCount int32                  `protobuf:"varint,2,opt,name=total_file_count,json=totalFileCount,proto3" json:"total_file_count,omitempty"`
+	TotalByteCount int64                  `protobuf:"varint,3,opt,name=total_byte_count,json=totalByteCount,proto3" json:"total_byte_count,omitempty"`
+	NodeLoads      []*NodeLoad            `protobuf:"bytes,4,rep,name=node_loads,json=nodeLoads,proto3" json:"node_loads,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PlanTopologyChangeResponse) Reset() {
+	*x = PlanTopologyChangeResponse{}
+	mi := &file_proto_admin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlanTopologyChangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlanTopologyChangeResponse) ProtoMessage() {}
+
+func (x *PlanTopologyChangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlanTopologyChangeResponse.ProtoReflect.Descriptor instead.
+func (*PlanTopologyChangeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PlanTopologyChangeResponse) GetTransfers() []*PlannedTransfer {
+	if x != nil {
+		return x.Transfers
+	}
+	return nil
+}
+
+func (x *PlanTopologyChangeResponse) GetTotalFileCount() int32 {
+	if x != nil {
+		return x.TotalFileCount
+	}
+	return 0
+}
+
+func (x *PlanTopologyChangeResponse) GetTotalByteCount() int64 {
+	if x != nil {
+		return x.TotalByteCount
+	}
+	return 0
+}
+
+func (x *PlanTopologyChangeResponse) GetNodeLoads() []*NodeLoad {
+	if x != nil {
+		return x.NodeLoads
+	}
+	return nil
+}
+
+// DrainNode stops new writes to a node, copies its files to their new owners
+// in the background, and removes it from the cluster once every copy has been
+// verified.
+type DrainNodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeAddress   string                 `protobuf:"bytes,1,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainNodeRequest) Reset() {
+	*x = DrainNodeRequest{}
+	mi := &file_proto_admin_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainNodeRequest) ProtoMessage() {}
+
+func (x *DrainNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainNodeRequest.ProtoReflect.Descriptor instead.
+func (*DrainNodeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DrainNodeRequest) GetNodeAddress() string {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return ""
+}
+
+type DrainNodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileCount     int32                  `protobuf:"varint,1,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
+	ByteCount     int64                  `protobuf:"varint,2,opt,name=byte_count,json=byteCount,proto3" json:"byte_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainNodeResponse) Reset() {
+	*x = DrainNodeResponse{}
+	mi := &file_proto_admin_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainNodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainNodeResponse) ProtoMessage() {}
+
+func (x *DrainNodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainNodeResponse.ProtoReflect.Descriptor instead.
+func (*DrainNodeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DrainNodeResponse) GetFileCount() int32 {
+	if x != nil {
+		return x.FileCount
+	}
+	return 0
+}
+
+func (x *DrainNodeResponse) GetByteCount() int64 {
+	if x != nil {
+		return x.ByteCount
+	}
+	return 0
+}
+
+type DrainStatus struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	NodeAddress       string                 `protobuf:"bytes,1,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	State             DrainState             `protobuf:"varint,2,opt,name=state,proto3,enum=tritontube.DrainState" json:"state,omitempty"`
+	TotalFileCount    int32                  `protobuf:"varint,3,opt,name=total_file_count,json=totalFileCount,proto3" json:"total_file_count,omitempty"`
+	MigratedFileCount int32                  `protobuf:"varint,4,opt,name=migrated_file_count,json=migratedFileCount,proto3" json:"migrated_file_count,omitempty"`
+	TotalByteCount    int64                  `protobuf:"varint,5,opt,name=total_byte_count,json=totalByteCount,proto3" json:"total_byte_count,omitempty"`
+	MigratedByteCount int64                  `protobuf:"varint,6,opt,name=migrated_byte_count,json=migratedByteCount,proto3" json:"migrated_byte_count,omitempty"`
+	Error             string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *DrainStatus) Reset() {
+	*x = DrainStatus{}
+	mi := &file_proto_admin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainStatus) ProtoMessage() {}
+
+func (x *DrainStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainStatus.ProtoReflect.Descriptor instead.
+func (*DrainStatus) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DrainStatus) GetNodeAddress() string {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return ""
+}
+
+func (x *DrainStatus) GetState() DrainState {
+	if x != nil {
+		return x.State
+	}
+	return DrainState_DRAINING
+}
+
+func (x *DrainStatus) GetTotalFileCount() int32 {
+	if x != nil {
+		return x.TotalFileCount
+	}
+	return 0
+}
+
+func (x *DrainStatus) GetMigratedFileCount() int32 {
+	if x != nil {
+		return x.MigratedFileCount
+	}
+	return 0
+}
+
+func (x *DrainStatus) GetTotalByteCount() int64 {
+	if x != nil {
+		return x.TotalByteCount
+	}
+	return 0
+}
+
+func (x *DrainStatus) GetMigratedByteCount() int64 {
+	if x != nil {
+		return x.MigratedByteCount
+	}
+	return 0
+}
+
+func (x *DrainStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetClusterStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetClusterStatusRequest) Reset() {
+	*x = GetClusterStatusRequest{}
+	mi := &file_proto_admin_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClusterStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetClusterStatusRequest) ProtoMessage() {}
+
+func (x *GetClusterStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetClusterStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetClusterStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{14}
+}
+
+type GetClusterStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ActiveNodes   []string               `protobuf:"bytes,1,rep,name=active_nodes,json=activeNodes,proto3" json:"active_nodes,omitempty"`
+	Drains        []*DrainStatus         `protobuf:"bytes,2,rep,name=drains,proto3" json:"drains,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetClusterStatusResponse) Reset() {
+	*x = GetClusterStatusResponse{}
+	mi := &file_proto_admin_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClusterStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetClusterStatusResponse) ProtoMessage() {}
+
+func (x *GetClusterStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetClusterStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetClusterStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetClusterStatusResponse) GetActiveNodes() []string {
+	if x != nil {
+		return x.ActiveNodes
+	}
+	return nil
+}
+
+func (x *GetClusterStatusResponse) GetDrains() []*DrainStatus {
+	if x != nil {
+		return x.Drains
+	}
+	return nil
+}
+
+// WatchRing sends the current ring, and then the whole ring again every time
+// it changes, so that web servers can route requests without owning it.
+type WatchRingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRingRequest) Reset() {
+	*x = WatchRingRequest{}
+	mi := &file_proto_admin_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRingRequest) ProtoMessage() {}
+
+func (x *WatchRingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRingRequest.ProtoReflect.Descriptor instead.
+func (*WatchRingRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{16}
+}
+
+type RingSnapshot struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// version goes up by one with every change.
+	Version uint64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// storage_servers lists every node in the cluster, including those being
+	// drained, which serve reads but take no new writes.
+	StorageServers []string `protobuf:"bytes,2,rep,name=storage_servers,json=storageServers,proto3" json:"storage_servers,omitempty"`
+	DrainingNodes  []string `protobuf:"bytes,3,rep,name=draining_nodes,json=drainingNodes,proto3" json:"draining_nodes,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RingSnapshot) Reset() {
+	*x = RingSnapshot{}
+	mi := &file_proto_admin_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RingSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RingSnapshot) ProtoMessage() {}
+
+func (x *RingSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RingSnapshot.ProtoReflect.Descriptor instead.
+func (*RingSnapshot) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RingSnapshot) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *RingSnapshot) GetStorageServers() []string {
+	if x != nil {
+		return x.StorageServers
+	}
+	return nil
+}
+
+func (x *RingSnapshot) GetDrainingNodes() []string {
+	if x != nil {
+		return x.DrainingNodes
+	}
+	return nil
+}
+
+var File_proto_admin_proto protoreflect.FileDescriptor
+
+const file_proto_admin_proto_rawDesc = "" +
+	"\n" +
+	"\x11proto/admin.proto\x12\n" +
+	"tritontube\"3\n" +
+	"\x0eAddNodeRequest\x12!\n" +
+	"\fnode_address\x18\x01 \x01(\tR\vnodeAddress\"A\n" +
+	"\x0fAddNodeResponse\x12.\n" +
+	"\x13migrated_file_count\x18\x01 \x01(\x05R\x11migratedFileCount\"6\n" +
+	"\x11RemoveNodeRequest\x12!\n" +
+	"\fnode_address\x18\x01 \x01(\tR\vnodeAddress\"D\n" +
+	"\x12RemoveNodeResponse\x12.\n" +
+	"\x13migrated_file_count\x18\x01 \x01(\x05R\x11migratedFileCount\"\x12\n" +
+	"\x10ListNodesRequest\"\x8e\x04\n" +
+	"\bNodeInfo\x12!\n" +
+	"\fnode_address\x18\x01 \x01(\tR\vnodeAddress\x12.\n" +
+	"\x06health\x18\x02 \x01(\x0e2\x16.tritontube.NodeHealthR\x06health\x121\n" +
+	"\x14consecutive_failures\x18\x03 \x01(\x05R\x13consecutiveFailures\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x04 \x01(\tR\tlastError\x12#\n" +
+	"\rring_position\x18\x05 \x01(\x04R\fringPosition\x12&\n" +
+	"\x0fkey_space_share\x18\x06 \x01(\x01R\rkeySpaceShare\x12\x1a\n" +
+	"\bdraining\x18\a \x01(\bR\bdraining\x12\x1d\n" +
+	"\n" +
+	"file_count\x18\b \x01(\x03R\tfileCount\x12\x1d\n" +
+	"\n" +
+	"bytes_used\x18\t \x01(\x03R\tbytesUsed\x12\x1d\n" +
+	"\n" +
+	"bytes_free\x18\n" +
+	" \x01(\x03R\tbytesFree\x12%\n" +
+	"\x0euptime_seconds\x18\v \x01(\x03R\ruptimeSeconds\x12\x18\n" +
+	"\aversion\x18\f \x01(\tR\aversion\x12!\n" +
+	"\fstatus_error\x18\r \x01(\tR\vstatusError\x12\x1f\n" +
+	"\vquota_bytes\x18\x0e \x01(\x03R\n" +
+	"quotaBytes\x12\x12\n" +
+	"\x04full\x18\x0f \x01(\bR\x04full\"^\n" +
+	"\x11ListNodesResponse\x12\x14\n" +
+	"\x05nodes\x18\x01 \x03(\tR\x05nodes\x123\n" +
+	"\n" +
+	"node_infos\x18\x02 \x03(\v2\x14.tritontube.NodeInfoR\tnodeInfos\"{\n" +
+	"\x19PlanTopologyChangeRequest\x12;\n" +
+	"\toperation\x18\x01 \x01(\x0e2\x1d.tritontube.TopologyOperationR\toperation\x12!\n" +
+	"\fnode_address\x18\x02 \x01(\tR\vnodeAddress\"\x89\x01\n" +
+	"\x0fPlannedTransfer\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\x12\x1d\n" +
+	"\n" +
+	"file_count\x18\x03 \x01(\x05R\tfileCount\x12\x1d\n" +
+	"\n" +
+	"byte_count\x18\x04 \x01(\x03R\tbyteCount\"k\n" +
+	"\bNodeLoad\x12!\n" +
+	"\fnode_address\x18\x01 \x01(\tR\vnodeAddress\x12\x1d\n" +
+	"\n" +
+	"file_count\x18\x02 \x01(\x05R\tfileCount\x12\x1d\n" +
+	"\n" +
+	"byte_count\x18\x03 \x01(\x03R\tbyteCount\"\xe0\x01\n" +
+	"\x1aPlanTopologyChangeResponse\x129\n" +
+	"\ttransfers\x18\x01 \x03(\v2\x1b.tritontube.PlannedTransferR\ttransfers\x12(\n" +
+	"\x10total_file_count\x18\x02 \x01(\x05R\x0etotalFileCount\x12(\n" +
+	"\x10total_byte_count\x18\x03 \x01(\x03R\x0etotalByteCount\x123\n" +
+	"\n" +
+	"node_loads\x18\x04 \x03(\v2\x14.tritontube.NodeLoadR\tnodeLoads\"5\n" +
+	"\x10DrainNodeRequest\x12!\n" +
+	"\fnode_address\x18\x01 \x01(\tR\vnodeAddress\"Q\n" +
+	"\x11DrainNodeResponse\x12\x1d\n" +
+	"\n" +
+	"file_count\x18\x01 \x01(\x05R\tfileCount\x12\x1d\n" +
+	"\n" +
+	"byte_count\x18\x02 \x01(\x03R\tbyteCount\"\xa8\x02\n" +
+	"\vDrainStatus\x12!\n" +
+	"\fnode_address\x18\x01 \x01(\tR\vnodeAddress\x12,\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x16.tritontube.DrainStateR\x05state\x12(\n" +
+	"\x10total_file_count\x18\x03 \x01(\x05R\x0etotalFileCount\x12.\n" +
+	"\x13migrated_file_count\x18\x04 \x01(\x05R\x11migratedFileCount\x12(\n" +
+	"\x10total_byte_count\x18\x05 \x01(\x03R\x0etotalByteCount\x12.\n" +
+	"\x13migrated_byte_count\x18\x06 \x01(\x03R\x11migratedByteCount\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\"\x19\n" +
+	"\x17GetClusterStatusRequest\"n\n" +
+	"\x18GetClusterStatusResponse\x12!\n" +
+	"\factive_nodes\x18\x01 \x03(\tR\vactiveNodes\x12/\n" +
+	"\x06drains\x18\x02 \x03(\v2\x17.tritontube.DrainStatusR\x06drains\"\x12\n" +
+	"\x10WatchRingRequest\"x\n" +
+	"\fRingSnapshot\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12'\n" +
+	"\x0fstorage_servers\x18\x02 \x03(\tR\x0estorageServers\x12%\n" +
+	"\x0edraining_nodes\x18\x03 \x03(\tR\rdrainingNodes*?\n" +
+	"\n" +
+	"NodeHealth\x12\x12\n" +
+	"\x0eHEALTH_UNKNOWN\x10\x00\x12\x06\n" +
+	"\x02UP\x10\x01\x12\v\n" +
+	"\aSUSPECT\x10\x02\x12\b\n" +
+	"\x04DOWN\x10\x03*(\n" +
+	"\x11TopologyOperation\x12\a\n" +
+	"\x03ADD\x10\x00\x12\n" +
+	"\n" +
+	"\x06REMOVE\x10\x01*9\n" +
+	"\n" +
+	"DrainState\x12\f\n" +
+	"\bDRAINING\x10\x00\x12\v\n" +
+	"\aDRAINED\x10\x01\x12\x10\n" +
+	"\fDRAIN_FAILED\x10\x022\xca\x04\n" +
 	"\x18VideoContentAdminService\x12B\n" +
 	"\aAddNode\x12\x1a.tritontube.AddNodeRequest\x1a\x1b.tritontube.AddNodeResponse\x12K\n" +
 	"\n" +
 	"RemoveNode\x12\x1d.tritontube.RemoveNodeRequest\x1a\x1e.tritontube.RemoveNodeResponse\x12H\n" +
-	"\tListNodes\x12\x1c.tritontube.ListNodesRequest\x1a\x1d.tritontube.ListNodesResponseB\x16Z\x14internal/proto;protob\x06proto3"
+	"\tListNodes\x12\x1c.tritontube.ListNodesRequest\x1a\x1d.tritontube.ListNodesResponse\x12c\n" +
+	"\x12PlanTopologyChange\x12%.tritontube.PlanTopologyChangeRequest\x1a&.tritontube.PlanTopologyChangeResponse\x12H\n" +
+	"\tDrainNode\x12\x1c.tritontube.DrainNodeRequest\x1a\x1d.tritontube.DrainNodeResponse\x12]\n" +
+	"\x10GetClusterStatus\x12#.tritontube.GetClusterStatusRequest\x1a$.tritontube.GetClusterStatusResponse\x12E\n" +
+	"\tWatchRing\x12\x1c.tritontube.WatchRingRequest\x1a\x18.tritontube.RingSnapshot0\x01B\x16Z\x14internal/proto;protob\x06proto3"
 
 var (
 	file_proto_admin_proto_rawDescOnce sync.Once
@@ -312,27 +1342,58 @@ func file_proto_admin_proto_rawDescGZIP() []byte {
 	return file_proto_admin_proto_rawDescData
 }
 
-var file_proto_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_admin_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_proto_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_proto_admin_proto_goTypes = []any{
-	(*AddNodeRequest)(nil),     // 0: tritontube.AddNodeRequest
-	(*AddNodeResponse)(nil),    // 1: tritontube.AddNodeResponse
-	(*RemoveNodeRequest)(nil),  // 2: tritontube.RemoveNodeRequest
-	(*RemoveNodeResponse)(nil), // 3: tritontube.RemoveNodeResponse
-	(*ListNodesRequest)(nil),   // 4: tritontube.ListNodesRequest
-	(*ListNodesResponse)(nil),  // 5: tritontube.ListNodesResponse
+	(NodeHealth)(0),                    // 0: tritontube.NodeHealth
+	(TopologyOperation)(0),             // 1: tritontube.TopologyOperation
+	(DrainState)(0),                    // 2: tritontube.DrainState
+	(*AddNodeRequest)(nil),             // 3: tritontube.AddNodeRequest
+	(*AddNodeResponse)(nil),            // 4: tritontube.AddNodeResponse
+	(*RemoveNodeRequest)(nil),          // 5: tritontube.RemoveNodeRequest
+	(*RemoveNodeResponse)(nil),         // 6: tritontube.RemoveNodeResponse
+	(*ListNodesRequest)(nil),           // 7: tritontube.ListNodesRequest
+	(*NodeInfo)(nil),                   // 8: tritontube.NodeInfo
+	(*ListNodesResponse)(nil),          // 9: tritontube.ListNodesResponse
+	(*PlanTopologyChangeRequest)(nil),  // 10: tritontube.PlanTopologyChangeRequest
+	(*PlannedTransfer)(nil),            // 11: tritontube.PlannedTransfer
+	(*NodeLoad)(nil),                   // 12: tritontube.NodeLoad
+	(*PlanTopologyChangeResponse)(nil), // 13: tritontube.PlanTopologyChangeResponse
+	(*DrainNodeRequest)(nil),           // 14: tritontube.DrainNodeRequest
+	(*DrainNodeResponse)(nil),          // 15: tritontube.DrainNodeResponse
+	(*DrainStatus)(nil),                // 16: tritontube.DrainStatus
+	(*GetClusterStatusRequest)(nil),    // 17: tritontube.GetClusterStatusRequest
+	(*GetClusterStatusResponse)(nil),   // 18: tritontube.GetClusterStatusResponse
+	(*WatchRingRequest)(nil),           // 19: tritontube.WatchRingRequest
+	(*RingSnapshot)(nil),               // 20: tritontube.RingSnapshot
 }
 var file_proto_admin_proto_depIdxs = []int32{
-	0, // 0: tritontube.VideoContentAdminService.AddNode:input_type -> tritontube.AddNodeRequest
-	2, // 1: tritontube.VideoContentAdminService.RemoveNode:input_type -> tritontube.RemoveNodeRequest
-	4, // 2: tritontube.VideoContentAdminService.ListNodes:input_type -> tritontube.ListNodesRequest
-	1, // 3: tritontube.VideoContentAdminService.AddNode:output_type -> tritontube.AddNodeResponse
-	3, // 4: tritontube.VideoContentAdminService.RemoveNode:output_type -> tritontube.RemoveNodeResponse
-	5, // 5: tritontube.VideoContentAdminService.ListNodes:output_type -> tritontube.ListNodesResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: tritontube.NodeInfo.health:type_name -> tritontube.NodeHealth
+	8,  // 1: tritontube.ListNodesResponse.node_infos:type_name -> tritontube.NodeInfo
+	1,  // 2: tritontube.PlanTopologyChangeRequest.operation:type_name -> tritontube.TopologyOperation
+	11, // 3: tritontube.PlanTopologyChangeResponse.transfers:type_name -> tritontube.PlannedTransfer
+	12, // 4: tritontube.PlanTopologyChangeResponse.node_loads:type_name -> tritontube.NodeLoad
+	2,  // 5: tritontube.DrainStatus.state:type_name -> tritontube.DrainState
+	16, // 6: tritontube.GetClusterStatusResponse.drains:type_name -> tritontube.DrainStatus
+	3,  // 7: tritontube.VideoContentAdminService.AddNode:input_type -> tritontube.AddNodeRequest
+	5,  // 8: tritontube.VideoContentAdminService.RemoveNode:input_type -> tritontube.RemoveNodeRequest
+	7,  // 9: tritontube.VideoContentAdminService.ListNodes:input_type -> tritontube.ListNodesRequest
+	10, // 10: tritontube.VideoContentAdminService.PlanTopologyChange:input_type -> tritontube.PlanTopologyChangeRequest
+	14, // 11: tritontube.VideoContentAdminService.DrainNode:input_type -> tritontube.DrainNodeRequest
+	17, // 12: tritontube.VideoContentAdminService.GetClusterStatus:input_type -> tritontube.GetClusterStatusRequest
+	19, // 13: tritontube.VideoContentAdminService.WatchRing:input_type -> tritontube.WatchRingRequest
+	4,  // 14: tritontube.VideoContentAdminService.AddNode:output_type -> tritontube.AddNodeResponse
+	6,  // 15: tritontube.VideoContentAdminService.RemoveNode:output_type -> tritontube.RemoveNodeResponse
+	9,  // 16: tritontube.VideoContentAdminService.ListNodes:output_type -> tritontube.ListNodesResponse
+	13, // 17: tritontube.VideoContentAdminService.PlanTopologyChange:output_type -> tritontube.PlanTopologyChangeResponse
+	15, // 18: tritontube.VideoContentAdminService.DrainNode:output_type -> tritontube.DrainNodeResponse
+	18, // 19: tritontube.VideoContentAdminService.GetClusterStatus:output_type -> tritontube.GetClusterStatusResponse
+	20, // 20: tritontube.VideoContentAdminService.WatchRing:output_type -> tritontube.RingSnapshot
+	14, // [14:21] is the sub-list for method output_type
+	7,  // [7:14] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_proto_admin_proto_init() }
@@ -345,13 +1406,14 @@ func file_proto_admin_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_admin_proto_rawDesc), len(file_proto_admin_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   6,
+			NumEnums:      3,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_admin_proto_goTypes,
 		DependencyIndexes: file_proto_admin_proto_depIdxs,
+		EnumInfos:         file_proto_admin_proto_enumTypes,
 		MessageInfos:      file_proto_admin_proto_msgTypes,
 	}.Build()
 	File_proto_admin_proto = out.File
@@ -68,6 +68,7 @@ func (x *ReadRequest) GetFileId() string {
 type ReadResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Sha256        []byte                 `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -109,10 +110,20 @@ func (x *ReadResponse) GetData() []byte {
 	return nil
 }
 
+func (x *ReadResponse) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+// sha256 is optional. When set, the server rejects the write if the data
+// does not match it.
 type WriteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
 	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Sha256        []byte                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -161,6 +172,13 @@ func (x *WriteRequest) GetData() []byte {
 	return nil
 }
 
+func (x *WriteRequest) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
 type WriteResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -197,8 +215,17 @@ func (*WriteResponse) Descriptor() ([]byte, []int) {
 	return file_proto_nw_proto_rawDescGZIP(), []int{3}
 }
 
+// List returns files in an order that is stable for the node's backend, at
+// most page_size at a time. Pass the next_page_token of a response as page_token
+// to get the following page; it is empty on the last page. A page_size of
+// zero uses the server's default.
 type ListRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	VideoIdPrefix string                 `protobuf:"bytes,1,opt,name=video_id_prefix,json=videoIdPrefix,proto3" json:"video_id_prefix,omitempty"`
+	PageToken     string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	PageSize      int32                  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// Optional. Only files whose ring position falls in the range are listed.
+	HashRange     *HashRange `protobuf:"bytes,4,opt,name=hash_range,json=hashRange,proto3" json:"hash_range,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -233,16 +260,152 @@ func (*ListRequest) Descriptor() ([]byte, []int) {
 	return file_proto_nw_proto_rawDescGZIP(), []int{4}
 }
 
+func (x *ListRequest) GetVideoIdPrefix() string {
+	if x != nil {
+		return x.VideoIdPrefix
+	}
+	return ""
+}
+
+func (x *ListRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListRequest) GetHashRange() *HashRange {
+	if x != nil {
+		return x.HashRange
+	}
+	return nil
+}
+
+// HashRange covers the ring positions in [start, end), wrapping around the
+// end of the ring when start >= end. start == end covers the whole ring.
+type HashRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         uint64                 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           uint64                 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HashRange) Reset() {
+	*x = HashRange{}
+	mi := &file_proto_nw_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HashRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashRange) ProtoMessage() {}
+
+func (x *HashRange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashRange.ProtoReflect.Descriptor instead.
+func (*HashRange) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HashRange) GetStart() uint64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *HashRange) GetEnd() uint64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_proto_nw_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FileInfo) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *FileInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
 type ListResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FileIds       []string               `protobuf:"bytes,1,rep,name=file_ids,json=fileIds,proto3" json:"file_ids,omitempty"`
+	Files         []*FileInfo            `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,3,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListResponse) Reset() {
 	*x = ListResponse{}
-	mi := &file_proto_nw_proto_msgTypes[5]
+	mi := &file_proto_nw_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -254,7 +417,7 @@ func (x *ListResponse) String() string {
 func (*ListResponse) ProtoMessage() {}
 
 func (x *ListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nw_proto_msgTypes[5]
+	mi := &file_proto_nw_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -267,7 +430,7 @@ func (x *ListResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
 func (*ListResponse) Descriptor() ([]byte, []int) {
-	return file_proto_nw_proto_rawDescGZIP(), []int{5}
+	return file_proto_nw_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ListResponse) GetFileIds() []string {
@@ -277,6 +440,20 @@ func (x *ListResponse) GetFileIds() []string {
 	return nil
 }
 
+func (x *ListResponse) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ListResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
 type DeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
@@ -286,7 +463,7 @@ type DeleteRequest struct {
 
 func (x *DeleteRequest) Reset() {
 	*x = DeleteRequest{}
-	mi := &file_proto_nw_proto_msgTypes[6]
+	mi := &file_proto_nw_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -298,7 +475,7 @@ func (x *DeleteRequest) String() string {
 func (*DeleteRequest) ProtoMessage() {}
 
 func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nw_proto_msgTypes[6]
+	mi := &file_proto_nw_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -311,7 +488,7 @@ func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
 func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_nw_proto_rawDescGZIP(), []int{6}
+	return file_proto_nw_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *DeleteRequest) GetFileId() string {
@@ -329,7 +506,7 @@ type DeleteResponse struct {
 
 func (x *DeleteResponse) Reset() {
 	*x = DeleteResponse{}
-	mi := &file_proto_nw_proto_msgTypes[7]
+	mi := &file_proto_nw_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -341,7 +518,7 @@ func (x *DeleteResponse) String() string {
 func (*DeleteResponse) ProtoMessage() {}
 
 func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nw_proto_msgTypes[7]
+	mi := &file_proto_nw_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -354,34 +531,819 @@ func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
 func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_nw_proto_rawDescGZIP(), []int{7}
+	return file_proto_nw_proto_rawDescGZIP(), []int{9}
 }
 
-var File_proto_nw_proto protoreflect.FileDescriptor
+// Transfer pushes the given files from the receiving node directly to the
+// destination node, verifies the copies by checksum, and deletes the local
+// copies unless keep_source is set.
+type TransferRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	FileIds        []string               `protobuf:"bytes,1,rep,name=file_ids,json=fileIds,proto3" json:"file_ids,omitempty"`
+	Destination    string                 `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	Parallelism    int32                  `protobuf:"varint,3,opt,name=parallelism,proto3" json:"parallelism,omitempty"`
+	BytesPerSecond int64                  `protobuf:"varint,4,opt,name=bytes_per_second,json=bytesPerSecond,proto3" json:"bytes_per_second,omitempty"`
+	KeepSource     bool                   `protobuf:"varint,5,opt,name=keep_source,json=keepSource,proto3" json:"keep_source,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
 
-const file_proto_nw_proto_rawDesc = "" +
-	"\n" +
-	"\x0eproto/nw.proto\x12\n" +
-	"tritontube\"&\n" +
-	"\vReadRequest\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\"\"\n" +
-	"\fReadResponse\x12\x12\n" +
-	"\x04data\x18\x01 \x01(\fR\x04data\";\n" +
-	"\fWriteRequest\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x12\n" +
-	"\x04data\x18\x02 \x01(\fR\x04data\"\x0f\n" +
-	"\rWriteResponse\"\r\n" +
-	"\vListRequest\")\n" +
-	"\fListResponse\x12\x19\n" +
-	"\bfile_ids\x18\x01 \x03(\tR\afileIds\"(\n" +
-	"\rDeleteRequest\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\"\x10\n" +
-	"\x0eDeleteResponse2\x8a\x02\n" +
+func (x *TransferRequest) Reset() {
+	*x = TransferRequest{}
+	mi := &file_proto_nw_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferRequest) ProtoMessage() {}
+
+func (x *TransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferRequest.ProtoReflect.Descriptor instead.
+func (*TransferRequest) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TransferRequest) GetFileIds() []string {
+	if x != nil {
+		return x.FileIds
+	}
+	return nil
+}
+
+func (x *TransferRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *TransferRequest) GetParallelism() int32 {
+	if x != nil {
+		return x.Parallelism
+	}
+	return 0
+}
+
+func (x *TransferRequest) GetBytesPerSecond() int64 {
+	if x != nil {
+		return x.BytesPerSecond
+	}
+	return 0
+}
+
+func (x *TransferRequest) GetKeepSource() bool {
+	if x != nil {
+		return x.KeepSource
+	}
+	return false
+}
+
+type TransferResponse struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	TransferredFileCount int32                  `protobuf:"varint,1,opt,name=transferred_file_count,json=transferredFileCount,proto3" json:"transferred_file_count,omitempty"`
+	TransferredBytes     int64                  `protobuf:"varint,2,opt,name=transferred_bytes,json=transferredBytes,proto3" json:"transferred_bytes,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *TransferResponse) Reset() {
+	*x = TransferResponse{}
+	mi := &file_proto_nw_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferResponse) ProtoMessage() {}
+
+func (x *TransferResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferResponse.ProtoReflect.Descriptor instead.
+func (*TransferResponse) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TransferResponse) GetTransferredFileCount() int32 {
+	if x != nil {
+		return x.TransferredFileCount
+	}
+	return 0
+}
+
+func (x *TransferResponse) GetTransferredBytes() int64 {
+	if x != nil {
+		return x.TransferredBytes
+	}
+	return 0
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_proto_nw_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{12}
+}
+
+type StatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileCount     int64                  `protobuf:"varint,1,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
+	BytesUsed     int64                  `protobuf:"varint,2,opt,name=bytes_used,json=bytesUsed,proto3" json:"bytes_used,omitempty"`
+	BytesFree     int64                  `protobuf:"varint,3,opt,name=bytes_free,json=bytesFree,proto3" json:"bytes_free,omitempty"`
+	UptimeSeconds int64                  `protobuf:"varint,4,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	Version       string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	// quota_bytes is zero when the node has no quota.
+	QuotaBytes int64 `protobuf:"varint,6,opt,name=quota_bytes,json=quotaBytes,proto3" json:"quota_bytes,omitempty"`
+	// full is set when the node is close enough to its quota or to running
+	// out of disk that writers should place new files elsewhere.
+	Full          bool `protobuf:"varint,7,opt,name=full,proto3" json:"full,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	mi := &file_proto_nw_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StatusResponse) GetFileCount() int64 {
+	if x != nil {
+		return x.FileCount
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetBytesUsed() int64 {
+	if x != nil {
+		return x.BytesUsed
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetBytesFree() int64 {
+	if x != nil {
+		return x.BytesFree
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetQuotaBytes() int64 {
+	if x != nil {
+		return x.QuotaBytes
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetFull() bool {
+	if x != nil {
+		return x.Full
+	}
+	return false
+}
+
+type StatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatRequest) Reset() {
+	*x = StatRequest{}
+	mi := &file_proto_nw_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatRequest) ProtoMessage() {}
+
+func (x *StatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatRequest.ProtoReflect.Descriptor instead.
+func (*StatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *StatRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+type StatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        []byte                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatResponse) Reset() {
+	*x = StatResponse{}
+	mi := &file_proto_nw_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatResponse) ProtoMessage() {}
+
+func (x *StatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatResponse.ProtoReflect.Descriptor instead.
+func (*StatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *StatResponse) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *StatResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *StatResponse) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+type ScrubStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScrubStatusRequest) Reset() {
+	*x = ScrubStatusRequest{}
+	mi := &file_proto_nw_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrubStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrubStatusRequest) ProtoMessage() {}
+
+func (x *ScrubStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrubStatusRequest.ProtoReflect.Descriptor instead.
+func (*ScrubStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{16}
+}
+
+// Counts for the last pass are reset at the start of every pass, while
+// corrupt_file_count covers every pass since the server started.
+// quarantined_file_ids lists the last 100 files quarantined.
+type ScrubStatusResponse struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	Running                  bool                   `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`
+	PassCount                int64                  `protobuf:"varint,2,opt,name=pass_count,json=passCount,proto3" json:"pass_count,omitempty"`
+	LastPassStartedUnix      int64                  `protobuf:"varint,3,opt,name=last_pass_started_unix,json=lastPassStartedUnix,proto3" json:"last_pass_started_unix,omitempty"`
+	LastPassFinishedUnix     int64                  `protobuf:"varint,4,opt,name=last_pass_finished_unix,json=lastPassFinishedUnix,proto3" json:"last_pass_finished_unix,omitempty"`
+	LastPassFileCount        int64                  `protobuf:"varint,5,opt,name=last_pass_file_count,json=lastPassFileCount,proto3" json:"last_pass_file_count,omitempty"`
+	LastPassByteCount        int64                  `protobuf:"varint,6,opt,name=last_pass_byte_count,json=lastPassByteCount,proto3" json:"last_pass_byte_count,omitempty"`
+	LastPassCorruptFileCount int64                  `protobuf:"varint,7,opt,name=last_pass_corrupt_file_count,json=lastPassCorruptFileCount,proto3" json:"last_pass_corrupt_file_count,omitempty"`
+	CorruptFileCount         int64                  `protobuf:"varint,8,opt,name=corrupt_file_count,json=corruptFileCount,proto3" json:"corrupt_file_count,omitempty"`
+	QuarantinedFileIds       []string               `protobuf:"bytes,9,rep,name=quarantined_file_ids,json=quarantinedFileIds,proto3" json:"quarantined_file_ids,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *ScrubStatusResponse) Reset() {
+	*x = ScrubStatusResponse{}
+	mi := &file_proto_nw_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrubStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrubStatusResponse) ProtoMessage() {}
+
+func (x *ScrubStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrubStatusResponse.ProtoReflect.Descriptor instead.
+func (*ScrubStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ScrubStatusResponse) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *ScrubStatusResponse) GetPassCount() int64 {
+	if x != nil {
+		return x.PassCount
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetLastPassStartedUnix() int64 {
+	if x != nil {
+		return x.LastPassStartedUnix
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetLastPassFinishedUnix() int64 {
+	if x != nil {
+		return x.LastPassFinishedUnix
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetLastPassFileCount() int64 {
+	if x != nil {
+		return x.LastPassFileCount
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetLastPassByteCount() int64 {
+	if x != nil {
+		return x.LastPassByteCount
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetLastPassCorruptFileCount() int64 {
+	if x != nil {
+		return x.LastPassCorruptFileCount
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetCorruptFileCount() int64 {
+	if x != nil {
+		return x.CorruptFileCount
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetQuarantinedFileIds() []string {
+	if x != nil {
+		return x.QuarantinedFileIds
+	}
+	return nil
+}
+
+// DeleteVideo removes every file of a video at once. Readers see either all
+// of the video's files or none of them.
+type DeleteVideoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VideoId       string                 `protobuf:"bytes,1,opt,name=video_id,json=videoId,proto3" json:"video_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteVideoRequest) Reset() {
+	*x = DeleteVideoRequest{}
+	mi := &file_proto_nw_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteVideoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteVideoRequest) ProtoMessage() {}
+
+func (x *DeleteVideoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteVideoRequest.ProtoReflect.Descriptor instead.
+func (*DeleteVideoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DeleteVideoRequest) GetVideoId() string {
+	if x != nil {
+		return x.VideoId
+	}
+	return ""
+}
+
+type DeleteVideoResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	DeletedFileCount int32                  `protobuf:"varint,1,opt,name=deleted_file_count,json=deletedFileCount,proto3" json:"deleted_file_count,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *DeleteVideoResponse) Reset() {
+	*x = DeleteVideoResponse{}
+	mi := &file_proto_nw_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteVideoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteVideoResponse) ProtoMessage() {}
+
+func (x *DeleteVideoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteVideoResponse.ProtoReflect.Descriptor instead.
+func (*DeleteVideoResponse) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DeleteVideoResponse) GetDeletedFileCount() int32 {
+	if x != nil {
+		return x.DeletedFileCount
+	}
+	return 0
+}
+
+// Capacity is cheap enough to poll, unlike Status, which counts every file.
+type CapacityRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapacityRequest) Reset() {
+	*x = CapacityRequest{}
+	mi := &file_proto_nw_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapacityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapacityRequest) ProtoMessage() {}
+
+func (x *CapacityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapacityRequest.ProtoReflect.Descriptor instead.
+func (*CapacityRequest) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{20}
+}
+
+type CapacityResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	BytesFree int64                  `protobuf:"varint,1,opt,name=bytes_free,json=bytesFree,proto3" json:"bytes_free,omitempty"`
+	// quota_bytes is zero when the node has no quota, in which case
+	// quota_bytes_used is not tracked.
+	QuotaBytes     int64 `protobuf:"varint,2,opt,name=quota_bytes,json=quotaBytes,proto3" json:"quota_bytes,omitempty"`
+	QuotaBytesUsed int64 `protobuf:"varint,3,opt,name=quota_bytes_used,json=quotaBytesUsed,proto3" json:"quota_bytes_used,omitempty"`
+	Full           bool  `protobuf:"varint,4,opt,name=full,proto3" json:"full,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CapacityResponse) Reset() {
+	*x = CapacityResponse{}
+	mi := &file_proto_nw_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapacityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapacityResponse) ProtoMessage() {}
+
+func (x *CapacityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nw_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapacityResponse.ProtoReflect.Descriptor instead.
+func (*CapacityResponse) Descriptor() ([]byte, []int) {
+	return file_proto_nw_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CapacityResponse) GetBytesFree() int64 {
+	if x != nil {
+		return x.BytesFree
+	}
+	return 0
+}
+
+func (x *CapacityResponse) GetQuotaBytes() int64 {
+	if x != nil {
+		return x.QuotaBytes
+	}
+	return 0
+}
+
+func (x *CapacityResponse) GetQuotaBytesUsed() int64 {
+	if x != nil {
+		return x.QuotaBytesUsed
+	}
+	return 0
+}
+
+func (x *CapacityResponse) GetFull() bool {
+	if x != nil {
+		return x.Full
+	}
+	return false
+}
+
+var File_proto_nw_proto protoreflect.FileDescriptor
+
+const file_proto_nw_proto_rawDesc = "" +
+	"\n" +
+	"\x0eproto/nw.proto\x12\n" +
+	"tritontube\"&\n" +
+	"\vReadRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\":\n" +
+	"\fReadResponse\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x16\n" +
+	"\x06sha256\x18\x02 \x01(\fR\x06sha256\"S\n" +
+	"\fWriteRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\fR\x06sha256\"\x0f\n" +
+	"\rWriteResponse\"\xa7\x01\n" +
+	"\vListRequest\x12&\n" +
+	"\x0fvideo_id_prefix\x18\x01 \x01(\tR\rvideoIdPrefix\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x124\n" +
+	"\n" +
+	"hash_range\x18\x04 \x01(\v2\x15.tritontube.HashRangeR\thashRange\"3\n" +
+	"\tHashRange\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x04R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x04R\x03end\"7\n" +
+	"\bFileInfo\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\"}\n" +
+	"\fListResponse\x12\x19\n" +
+	"\bfile_ids\x18\x01 \x03(\tR\afileIds\x12*\n" +
+	"\x05files\x18\x02 \x03(\v2\x14.tritontube.FileInfoR\x05files\x12&\n" +
+	"\x0fnext_page_token\x18\x03 \x01(\tR\rnextPageToken\"(\n" +
+	"\rDeleteRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\"\x10\n" +
+	"\x0eDeleteResponse\"\xbb\x01\n" +
+	"\x0fTransferRequest\x12\x19\n" +
+	"\bfile_ids\x18\x01 \x03(\tR\afileIds\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\x12 \n" +
+	"\vparallelism\x18\x03 \x01(\x05R\vparallelism\x12(\n" +
+	"\x10bytes_per_second\x18\x04 \x01(\x03R\x0ebytesPerSecond\x12\x1f\n" +
+	"\vkeep_source\x18\x05 \x01(\bR\n" +
+	"keepSource\"u\n" +
+	"\x10TransferResponse\x124\n" +
+	"\x16transferred_file_count\x18\x01 \x01(\x05R\x14transferredFileCount\x12+\n" +
+	"\x11transferred_bytes\x18\x02 \x01(\x03R\x10transferredBytes\"\x0f\n" +
+	"\rStatusRequest\"\xe3\x01\n" +
+	"\x0eStatusResponse\x12\x1d\n" +
+	"\n" +
+	"file_count\x18\x01 \x01(\x03R\tfileCount\x12\x1d\n" +
+	"\n" +
+	"bytes_used\x18\x02 \x01(\x03R\tbytesUsed\x12\x1d\n" +
+	"\n" +
+	"bytes_free\x18\x03 \x01(\x03R\tbytesFree\x12%\n" +
+	"\x0euptime_seconds\x18\x04 \x01(\x03R\ruptimeSeconds\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\x12\x1f\n" +
+	"\vquota_bytes\x18\x06 \x01(\x03R\n" +
+	"quotaBytes\x12\x12\n" +
+	"\x04full\x18\a \x01(\bR\x04full\"&\n" +
+	"\vStatRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\"S\n" +
+	"\fStatResponse\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\fR\x06sha256\"\x14\n" +
+	"\x12ScrubStatusRequest\"\xbc\x03\n" +
+	"\x13ScrubStatusResponse\x12\x18\n" +
+	"\arunning\x18\x01 \x01(\bR\arunning\x12\x1d\n" +
+	"\n" +
+	"pass_count\x18\x02 \x01(\x03R\tpassCount\x123\n" +
+	"\x16last_pass_started_unix\x18\x03 \x01(\x03R\x13lastPassStartedUnix\x125\n" +
+	"\x17last_pass_finished_unix\x18\x04 \x01(\x03R\x14lastPassFinishedUnix\x12/\n" +
+	"\x14last_pass_file_count\x18\x05 \x01(\x03R\x11lastPassFileCount\x12/\n" +
+	"\x14last_pass_byte_count\x18\x06 \x01(\x03R\x11lastPassByteCount\x12>\n" +
+	"\x1clast_pass_corrupt_file_count\x18\a \x01(\x03R\x18lastPassCorruptFileCount\x12,\n" +
+	"\x12corrupt_file_count\x18\b \x01(\x03R\x10corruptFileCount\x120\n" +
+	"\x14quarantined_file_ids\x18\t \x03(\tR\x12quarantinedFileIds\"/\n" +
+	"\x12DeleteVideoRequest\x12\x19\n" +
+	"\bvideo_id\x18\x01 \x01(\tR\avideoId\"C\n" +
+	"\x13DeleteVideoResponse\x12,\n" +
+	"\x12deleted_file_count\x18\x01 \x01(\x05R\x10deletedFileCount\"\x11\n" +
+	"\x0fCapacityRequest\"\x90\x01\n" +
+	"\x10CapacityResponse\x12\x1d\n" +
+	"\n" +
+	"bytes_free\x18\x01 \x01(\x03R\tbytesFree\x12\x1f\n" +
+	"\vquota_bytes\x18\x02 \x01(\x03R\n" +
+	"quotaBytes\x12(\n" +
+	"\x10quota_bytes_used\x18\x03 \x01(\x03R\x0equotaBytesUsed\x12\x12\n" +
+	"\x04full\x18\x04 \x01(\bR\x04full2\xb4\x05\n" +
 	"\x13NetworkVideoContent\x129\n" +
 	"\x04Read\x12\x17.tritontube.ReadRequest\x1a\x18.tritontube.ReadResponse\x12<\n" +
 	"\x05Write\x12\x18.tritontube.WriteRequest\x1a\x19.tritontube.WriteResponse\x129\n" +
 	"\x04List\x12\x17.tritontube.ListRequest\x1a\x18.tritontube.ListResponse\x12?\n" +
-	"\x06Delete\x12\x19.tritontube.DeleteRequest\x1a\x1a.tritontube.DeleteResponseB\x16Z\x14internal/proto;protob\x06proto3"
+	"\x06Delete\x12\x19.tritontube.DeleteRequest\x1a\x1a.tritontube.DeleteResponse\x12E\n" +
+	"\bTransfer\x12\x1b.tritontube.TransferRequest\x1a\x1c.tritontube.TransferResponse\x12?\n" +
+	"\x06Status\x12\x19.tritontube.StatusRequest\x1a\x1a.tritontube.StatusResponse\x129\n" +
+	"\x04Stat\x12\x17.tritontube.StatRequest\x1a\x18.tritontube.StatResponse\x12N\n" +
+	"\vScrubStatus\x12\x1e.tritontube.ScrubStatusRequest\x1a\x1f.tritontube.ScrubStatusResponse\x12N\n" +
+	"\vDeleteVideo\x12\x1e.tritontube.DeleteVideoRequest\x1a\x1f.tritontube.DeleteVideoResponse\x12E\n" +
+	"\bCapacity\x12\x1b.tritontube.CapacityRequest\x1a\x1c.tritontube.CapacityResponseB\x16Z\x14internal/proto;protob\x06proto3"
 
 var (
 	file_proto_nw_proto_rawDescOnce sync.Once
@@ -395,31 +1357,59 @@ func file_proto_nw_proto_rawDescGZIP() []byte {
 	return file_proto_nw_proto_rawDescData
 }
 
-var file_proto_nw_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_nw_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
 var file_proto_nw_proto_goTypes = []any{
-	(*ReadRequest)(nil),    // 0: tritontube.ReadRequest
-	(*ReadResponse)(nil),   // 1: tritontube.ReadResponse
-	(*WriteRequest)(nil),   // 2: tritontube.WriteRequest
-	(*WriteResponse)(nil),  // 3: tritontube.WriteResponse
-	(*ListRequest)(nil),    // 4: tritontube.ListRequest
-	(*ListResponse)(nil),   // 5: tritontube.ListResponse
-	(*DeleteRequest)(nil),  // 6: tritontube.DeleteRequest
-	(*DeleteResponse)(nil), // 7: tritontube.DeleteResponse
+	(*ReadRequest)(nil),         // 0: tritontube.ReadRequest
+	(*ReadResponse)(nil),        // 1: tritontube.ReadResponse
+	(*WriteRequest)(nil),        // 2: tritontube.WriteRequest
+	(*WriteResponse)(nil),       // 3: tritontube.WriteResponse
+	(*ListRequest)(nil),         // 4: tritontube.ListRequest
+	(*HashRange)(nil),           // 5: tritontube.HashRange
+	(*FileInfo)(nil),            // 6: tritontube.FileInfo
+	(*ListResponse)(nil),        // 7: tritontube.ListResponse
+	(*DeleteRequest)(nil),       // 8: tritontube.DeleteRequest
+	(*DeleteResponse)(nil),      // 9: tritontube.DeleteResponse
+	(*TransferRequest)(nil),     // 10: tritontube.TransferRequest
+	(*TransferResponse)(nil),    // 11: tritontube.TransferResponse
+	(*StatusRequest)(nil),       // 12: tritontube.StatusRequest
+	(*StatusResponse)(nil),      // 13: tritontube.StatusResponse
+	(*StatRequest)(nil),         // 14: tritontube.StatRequest
+	(*StatResponse)(nil),        // 15: tritontube.StatResponse
+	(*ScrubStatusRequest)(nil),  // 16: tritontube.ScrubStatusRequest
+	(*ScrubStatusResponse)(nil), // 17: tritontube.ScrubStatusResponse
+	(*DeleteVideoRequest)(nil),  // 18: tritontube.DeleteVideoRequest
+	(*DeleteVideoResponse)(nil), // 19: tritontube.DeleteVideoResponse
+	(*CapacityRequest)(nil),     // 20: tritontube.CapacityRequest
+	(*CapacityResponse)(nil),    // 21: tritontube.CapacityResponse
 }
 var file_proto_nw_proto_depIdxs = []int32{
-	0, // 0: tritontube.NetworkVideoContent.Read:input_type -> tritontube.ReadRequest
-	2, // 1: tritontube.NetworkVideoContent.Write:input_type -> tritontube.WriteRequest
-	4, // 2: tritontube.NetworkVideoContent.List:input_type -> tritontube.ListRequest
-	6, // 3: tritontube.NetworkVideoContent.Delete:input_type -> tritontube.DeleteRequest
-	1, // 4: tritontube.NetworkVideoContent.Read:output_type -> tritontube.ReadResponse
-	3, // 5: tritontube.NetworkVideoContent.Write:output_type -> tritontube.WriteResponse
-	5, // 6: tritontube.NetworkVideoContent.List:output_type -> tritontube.ListResponse
-	7, // 7: tritontube.NetworkVideoContent.Delete:output_type -> tritontube.DeleteResponse
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	5,  // 0: tritontube.ListRequest.hash_range:type_name -> tritontube.HashRange
+	6,  // 1: tritontube.ListResponse.files:type_name -> tritontube.FileInfo
+	0,  // 2: tritontube.NetworkVideoContent.Read:input_type -> tritontube.ReadRequest
+	2,  // 3: tritontube.NetworkVideoContent.Write:input_type -> tritontube.WriteRequest
+	4,  // 4: tritontube.NetworkVideoContent.List:input_type -> tritontube.ListRequest
+	8,  // 5: tritontube.NetworkVideoContent.Delete:input_type -> tritontube.DeleteRequest
+	10, // 6: tritontube.NetworkVideoContent.Transfer:input_type -> tritontube.TransferRequest
+	12, // 7: tritontube.NetworkVideoContent.Status:input_type -> tritontube.StatusRequest
+	14, // 8: tritontube.NetworkVideoContent.Stat:input_type -> tritontube.StatRequest
+	16, // 9: tritontube.NetworkVideoContent.ScrubStatus:input_type -> tritontube.ScrubStatusRequest
+	18, // 10: tritontube.NetworkVideoContent.DeleteVideo:input_type -> tritontube.DeleteVideoRequest
+	20, // 11: tritontube.NetworkVideoContent.Capacity:input_type -> tritontube.CapacityRequest
+	1,  // 12: tritontube.NetworkVideoContent.Read:output_type -> tritontube.ReadResponse
+	3,  // 13: tritontube.NetworkVideoContent.Write:output_type -> tritontube.WriteResponse
+	7,  // 14: tritontube.NetworkVideoContent.List:output_type -> tritontube.ListResponse
+	9,  // 15: tritontube.NetworkVideoContent.Delete:output_type -> tritontube.DeleteResponse
+	11, // 16: tritontube.NetworkVideoContent.Transfer:output_type -> tritontube.TransferResponse
+	13, // 17: tritontube.NetworkVideoContent.Status:output_type -> tritontube.StatusResponse
+	15, // 18: tritontube.NetworkVideoContent.Stat:output_type -> tritontube.StatResponse
+	17, // 19: tritontube.NetworkVideoContent.ScrubStatus:output_type -> tritontube.ScrubStatusResponse
+	19, // 20: tritontube.NetworkVideoContent.DeleteVideo:output_type -> tritontube.DeleteVideoResponse
+	21, // 21: tritontube.NetworkVideoContent.Capacity:output_type -> tritontube.CapacityResponse
+	12, // [12:22] is the sub-list for method output_type
+	2,  // [2:12] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proto_nw_proto_init() }
@@ -433,7 +1423,7 @@ func file_proto_nw_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_nw_proto_rawDesc), len(file_proto_nw_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   22,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,10 +19,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	NetworkVideoContent_Read_FullMethodName   = "/tritontube.NetworkVideoContent/Read"
-	NetworkVideoContent_Write_FullMethodName  = "/tritontube.NetworkVideoContent/Write"
-	NetworkVideoContent_List_FullMethodName   = "/tritontube.NetworkVideoContent/List"
-	NetworkVideoContent_Delete_FullMethodName = "/tritontube.NetworkVideoContent/Delete"
+	NetworkVideoContent_Read_FullMethodName        = "/tritontube.NetworkVideoContent/Read"
+	NetworkVideoContent_Write_FullMethodName       = "/tritontube.NetworkVideoContent/Write"
+	NetworkVideoContent_List_FullMethodName        = "/tritontube.NetworkVideoContent/List"
+	NetworkVideoContent_Delete_FullMethodName      = "/tritontube.NetworkVideoContent/Delete"
+	NetworkVideoContent_Transfer_FullMethodName    = "/tritontube.NetworkVideoContent/Transfer"
+	NetworkVideoContent_Status_FullMethodName      = "/tritontube.NetworkVideoContent/Status"
+	NetworkVideoContent_Stat_FullMethodName        = "/tritontube.NetworkVideoContent/Stat"
+	NetworkVideoContent_ScrubStatus_FullMethodName = "/tritontube.NetworkVideoContent/ScrubStatus"
+	NetworkVideoContent_DeleteVideo_FullMethodName = "/tritontube.NetworkVideoContent/DeleteVideo"
+	NetworkVideoContent_Capacity_FullMethodName    = "/tritontube.NetworkVideoContent/Capacity"
 )
 
 // NetworkVideoContentClient is the client API for NetworkVideoContent service.
@@ -33,6 +39,12 @@ type NetworkVideoContentClient interface {
 	Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*WriteResponse, error)
 	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResponse, error)
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error)
+	ScrubStatus(ctx context.Context, in *ScrubStatusRequest, opts ...grpc.CallOption) (*ScrubStatusResponse, error)
+	DeleteVideo(ctx context.Context, in *DeleteVideoRequest, opts ...grpc.CallOption) (*DeleteVideoResponse, error)
+	Capacity(ctx context.Context, in *CapacityRequest, opts ...grpc.CallOption) (*CapacityResponse, error)
 }
 
 type networkVideoContentClient struct {
@@ -83,6 +95,66 @@ func (c *networkVideoContentClient) Delete(ctx context.Context, in *DeleteReques
 	return out, nil
 }
 
+func (c *networkVideoContentClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransferResponse)
+	err := c.cc.Invoke(ctx, NetworkVideoContent_Transfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *networkVideoContentClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, NetworkVideoContent_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *networkVideoContentClient) Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatResponse)
+	err := c.cc.Invoke(ctx, NetworkVideoContent_Stat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *networkVideoContentClient) ScrubStatus(ctx context.Context, in *ScrubStatusRequest, opts ...grpc.CallOption) (*ScrubStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScrubStatusResponse)
+	err := c.cc.Invoke(ctx, NetworkVideoContent_ScrubStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *networkVideoContentClient) DeleteVideo(ctx context.Context, in *DeleteVideoRequest, opts ...grpc.CallOption) (*DeleteVideoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteVideoResponse)
+	err := c.cc.Invoke(ctx, NetworkVideoContent_DeleteVideo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *networkVideoContentClient) Capacity(ctx context.Context, in *CapacityRequest, opts ...grpc.CallOption) (*CapacityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CapacityResponse)
+	err := c.cc.Invoke(ctx, NetworkVideoContent_Capacity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NetworkVideoContentServer is the server API for NetworkVideoContent service.
 // All implementations must embed UnimplementedNetworkVideoContentServer
 // for forward compatibility.
@@ -91,6 +163,12 @@ type NetworkVideoContentServer interface {
 	Write(context.Context, *WriteRequest) (*WriteResponse, error)
 	List(context.Context, *ListRequest) (*ListResponse, error)
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	Transfer(context.Context, *TransferRequest) (*TransferResponse, error)
+	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	Stat(context.Context, *StatRequest) (*StatResponse, error)
+	ScrubStatus(context.Context, *ScrubStatusRequest) (*ScrubStatusResponse, error)
+	DeleteVideo(context.Context, *DeleteVideoRequest) (*DeleteVideoResponse, error)
+	Capacity(context.Context, *CapacityRequest) (*CapacityResponse, error)
 	mustEmbedUnimplementedNetworkVideoContentServer()
 }
 
@@ -113,6 +191,24 @@ func (UnimplementedNetworkVideoContentServer) List(context.Context, *ListRequest
 func (UnimplementedNetworkVideoContentServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedNetworkVideoContentServer) Transfer(context.Context, *TransferRequest) (*TransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedNetworkVideoContentServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedNetworkVideoContentServer) Stat(context.Context, *StatRequest) (*StatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stat not implemented")
+}
+func (UnimplementedNetworkVideoContentServer) ScrubStatus(context.Context, *ScrubStatusRequest) (*ScrubStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScrubStatus not implemented")
+}
+func (UnimplementedNetworkVideoContentServer) DeleteVideo(context.Context, *DeleteVideoRequest) (*DeleteVideoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVideo not implemented")
+}
+func (UnimplementedNetworkVideoContentServer) Capacity(context.Context, *CapacityRequest) (*CapacityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Capacity not implemented")
+}
 func (UnimplementedNetworkVideoContentServer) mustEmbedUnimplementedNetworkVideoContentServer() {}
 func (UnimplementedNetworkVideoContentServer) testEmbeddedByValue()                             {}
 
@@ -206,6 +302,114 @@ func _NetworkVideoContent_Delete_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NetworkVideoContent_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetworkVideoContentServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetworkVideoContent_Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetworkVideoContentServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetworkVideoContent_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetworkVideoContentServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetworkVideoContent_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetworkVideoContentServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetworkVideoContent_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetworkVideoContentServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetworkVideoContent_Stat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetworkVideoContentServer).Stat(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetworkVideoContent_ScrubStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScrubStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetworkVideoContentServer).ScrubStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetworkVideoContent_ScrubStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetworkVideoContentServer).ScrubStatus(ctx, req.(*ScrubStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetworkVideoContent_DeleteVideo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteVideoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetworkVideoContentServer).DeleteVideo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetworkVideoContent_DeleteVideo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetworkVideoContentServer).DeleteVideo(ctx, req.(*DeleteVideoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NetworkVideoContent_Capacity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapacityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetworkVideoContentServer).Capacity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetworkVideoContent_Capacity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetworkVideoContentServer).Capacity(ctx, req.(*CapacityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NetworkVideoContent_ServiceDesc is the grpc.ServiceDesc for NetworkVideoContent service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -229,6 +433,30 @@ var NetworkVideoContent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _NetworkVideoContent_Delete_Handler,
 		},
+		{
+			MethodName: "Transfer",
+			Handler:    _NetworkVideoContent_Transfer_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _NetworkVideoContent_Status_Handler,
+		},
+		{
+			MethodName: "Stat",
+			Handler:    _NetworkVideoContent_Stat_Handler,
+		},
+		{
+			MethodName: "ScrubStatus",
+			Handler:    _NetworkVideoContent_ScrubStatus_Handler,
+		},
+		{
+			MethodName: "DeleteVideo",
+			Handler:    _NetworkVideoContent_DeleteVideo_Handler,
+		},
+		{
+			MethodName: "Capacity",
+			Handler:    _NetworkVideoContent_Capacity_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/nw.proto",
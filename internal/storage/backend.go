@@ -0,0 +1,56 @@
+package storage
+
+import (
+	pb "tritontube/internal/proto"
+)
+
+// Backend stores the files of a storage node. File ids have already been
+// validated by the time they reach a backend. Missing files and videos are
+// reported with errors wrapping fs.ErrNotExist.
+type Backend interface {
+	// Read returns a file's contents and its recorded SHA-256. The checksum
+	// is nil for files stored before checksums were recorded.
+	Read(fileId string) ([]byte, []byte, error)
+	// Write stores a file along with its SHA-256, replacing any previous
+	// version. Readers never see a partially written file, nor one paired
+	// with the checksum of another version, even after a crash.
+	Write(fileId string, data []byte, checksum []byte) error
+	// Stat returns a file's size and its recorded SHA-256, which is nil as
+	// for Read.
+	Stat(fileId string) (int64, []byte, error)
+	Delete(fileId string) error
+	// DeleteVideo removes every file of a video.
+	DeleteVideo(videoId string) error
+	// Walk calls fn for every file whose video id starts with videoIdPrefix,
+	// in an order that is stable for the backend. If after is set, the walk
+	// starts with the file following it in that order. Returning fs.SkipAll
+	// from fn stops the walk.
+	Walk(videoIdPrefix string, after string, fn func(file *pb.FileInfo) error) error
+	Close() error
+}
+
+// tempCleaner is implemented by backends that a crash can leave with
+// temporary files.
+type tempCleaner interface {
+	CleanTemp() (int, error)
+}
+
+// spaceReporter is implemented by backends that know how much space is left.
+type spaceReporter interface {
+	BytesFree() (int64, error)
+}
+
+// checksumRecorder is implemented by backends that can hold files without a
+// recorded checksum, so that the scrubber can fill them in.
+type checksumRecorder interface {
+	SetChecksum(fileId string, checksum []byte) error
+}
+
+// backend returns the configured backend, or the local filesystem under Dir
+// if none was set.
+func (s *NetworkVideoContentServer) backend() Backend {
+	if s.Backend != nil {
+		return s.Backend
+	}
+	return FSBackend{Dir: s.Dir}
+}
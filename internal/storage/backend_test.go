@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	pb "tritontube/internal/proto"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/minio/minio-go/v7"
+)
+
+// testBackends open an empty backend of every kind, with S3 served by an
+// in-memory stand-in for MinIO.
+var testBackends = map[string]func(t *testing.T) Backend{
+	"fs": func(t *testing.T) Backend {
+		return FSBackend{Dir: t.TempDir()}
+	},
+	"bolt": func(t *testing.T) Backend {
+		backend, err := OpenBoltBackend(filepath.Join(t.TempDir(), "files.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { backend.Close() })
+		return backend
+	},
+	"s3": func(t *testing.T) Backend {
+		fake := gofakes3.New(s3mem.New()).Server()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// gofakes3 lists nothing given the empty delimiter minio-go sends
+			query := r.URL.Query()
+			if query.Has("delimiter") && query.Get("delimiter") == "" {
+				query.Del("delimiter")
+				r.URL.RawQuery = query.Encode()
+			}
+			fake.ServeHTTP(w, r)
+		}))
+		t.Cleanup(server.Close)
+		backend, err := OpenS3Backend(S3Options{
+			Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+			Bucket:    "tritontube",
+			AccessKey: "access",
+			SecretKey: "secret",
+			Region:    "us-east-1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return backend
+	},
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, backend Backend)) {
+	for name, open := range testBackends {
+		t.Run(name, func(t *testing.T) {
+			test(t, open(t))
+		})
+	}
+}
+
+func checksumOf(data string) []byte {
+	sum := sha256.Sum256([]byte(data))
+	return sum[:]
+}
+
+func writeFiles(t *testing.T, backend Backend, fileIds ...string) {
+	t.Helper()
+	for _, fileId := range fileIds {
+		if err := backend.Write(fileId, []byte(fileId), checksumOf(fileId)); err != nil {
+			t.Fatalf("Write %s: %v", fileId, err)
+		}
+	}
+}
+
+func walk(t *testing.T, backend Backend, videoIdPrefix string, after string) []string {
+	t.Helper()
+	var fileIds []string
+	err := backend.Walk(videoIdPrefix, after, func(file *pb.FileInfo) error {
+		fileIds = append(fileIds, file.GetFileId())
+		if file.GetSize() != int64(len(file.GetFileId())) {
+			t.Errorf("Walk reported size %d for %s", file.GetSize(), file.GetFileId())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	return fileIds
+}
+
+func TestBackendReadWrite(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		for _, data := range []string{"first", "second, which is longer"} {
+			if err := backend.Write("video/file", []byte(data), checksumOf(data)); err != nil {
+				t.Fatal(err)
+			}
+
+			read, checksum, err := backend.Read("video/file")
+			if err != nil || string(read) != data || !bytes.Equal(checksum, checksumOf(data)) {
+				t.Errorf("Read = %q, %x, %v, want %q and its checksum", read, checksum, err, data)
+			}
+			size, checksum, err := backend.Stat("video/file")
+			if err != nil || size != int64(len(data)) || !bytes.Equal(checksum, checksumOf(data)) {
+				t.Errorf("Stat = %d, %x, %v, want %d and the checksum of %q", size, checksum, err, len(data), data)
+			}
+		}
+	})
+}
+
+func TestBackendMissingFiles(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		writeFiles(t, backend, "video/file")
+
+		for _, fileId := range []string{"video/missing", "missing/file"} {
+			if _, _, err := backend.Read(fileId); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Read %s: %v, want fs.ErrNotExist", fileId, err)
+			}
+			if _, _, err := backend.Stat(fileId); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat %s: %v, want fs.ErrNotExist", fileId, err)
+			}
+			if err := backend.Delete(fileId); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Delete %s: %v, want fs.ErrNotExist", fileId, err)
+			}
+		}
+		if err := backend.DeleteVideo("missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("DeleteVideo of a missing video: %v, want fs.ErrNotExist", err)
+		}
+	})
+}
+
+func TestBackendDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		writeFiles(t, backend, "v1/a", "v1/b", "v10/a", "v2/a")
+
+		if err := backend.Delete("v1/a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := backend.Read("v1/a"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Read of a deleted file: %v, want fs.ErrNotExist", err)
+		}
+
+		if err := backend.DeleteVideo("v1"); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := walk(t, backend, "", ""), []string{"v10/a", "v2/a"}; !slices.Equal(got, want) {
+			t.Errorf("files left after DeleteVideo = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestBackendWalk(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		writeFiles(t, backend, "v2/a", "v1/b", "w1/a", "v1/a", "v2/c")
+
+		tests := []struct {
+			prefix string
+			after  string
+			want   []string
+		}{
+			{"", "", []string{"v1/a", "v1/b", "v2/a", "v2/c", "w1/a"}},
+			{"v", "", []string{"v1/a", "v1/b", "v2/a", "v2/c"}},
+			{"v2", "", []string{"v2/a", "v2/c"}},
+			{"", "v1/b", []string{"v2/a", "v2/c", "w1/a"}},
+			{"v", "v2/a", []string{"v2/c"}},
+			{"x", "", nil},
+		}
+		for _, test := range tests {
+			if got := walk(t, backend, test.prefix, test.after); !slices.Equal(got, test.want) {
+				t.Errorf("Walk(%q, %q) = %v, want %v", test.prefix, test.after, got, test.want)
+			}
+		}
+
+		var visited int
+		err := backend.Walk("", "", func(file *pb.FileInfo) error {
+			visited++
+			return fs.SkipAll
+		})
+		if err != nil || visited != 1 {
+			t.Errorf("Walk stopped by fs.SkipAll visited %d files: %v", visited, err)
+		}
+	})
+}
+
+// TestReadsDuringWritesMatchChecksums checks that readers never see a file
+// paired with the checksum of another version of it.
+func TestReadsDuringWritesMatchChecksums(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		server := &NetworkVideoContentServer{Backend: backend}
+		ctx := context.Background()
+		if _, err := server.Write(ctx, &pb.WriteRequest{FileId: "video/file", Data: []byte("initial")}); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				data := bytes.Repeat([]byte{byte(i)}, 100+i%10)
+				if _, err := server.Write(ctx, &pb.WriteRequest{FileId: "video/file", Data: data}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+
+		for range 200 {
+			read, err := server.Read(ctx, &pb.ReadRequest{FileId: "video/file"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum := sha256.Sum256(read.GetData()); !bytes.Equal(sum[:], read.GetSha256()) {
+				t.Fatalf("read data paired with the checksum of another version")
+			}
+			stat, err := server.Stat(ctx, &pb.StatRequest{FileId: "video/file"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stat.GetSha256()) != sha256.Size {
+				t.Fatalf("Stat returned checksum %x", stat.GetSha256())
+			}
+		}
+		close(stop)
+		wg.Wait()
+	})
+}
+
+// TestFSInterruptedWriteKeepsChecksum checks that a file left behind by a
+// write interrupted around the rename is still paired with its own checksum.
+func TestFSInterruptedWriteKeepsChecksum(t *testing.T) {
+	for _, left := range []string{"old", "new"} {
+		t.Run(left, func(t *testing.T) {
+			backend := FSBackend{Dir: t.TempDir()}
+			writeFiles(t, backend, "video/file")
+			old := "video/file"
+
+			// The state the write leaves just before or after renaming the data
+			root, err := os.OpenRoot(backend.Dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer root.Close()
+			if err := writeChecksums(root, "video/file", checksumOf("new"), checksumOf(old)); err != nil {
+				t.Fatal(err)
+			}
+			data := old
+			if left == "new" {
+				data = "new"
+				if err := os.WriteFile(filepath.Join(backend.Dir, "video", "file"), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			read, checksum, err := backend.Read("video/file")
+			if err != nil || string(read) != data || !bytes.Equal(checksum, checksumOf(data)) {
+				t.Errorf("Read = %q, %x, %v, want %q and its checksum", read, checksum, err, data)
+			}
+			size, checksum, err := backend.Stat("video/file")
+			if err != nil || size != int64(len(data)) || !bytes.Equal(checksum, checksumOf(data)) {
+				t.Errorf("Stat = %d, %x, %v, want %d and the checksum of %q", size, checksum, err, len(data), data)
+			}
+
+			server := &NetworkVideoContentServer{Backend: backend}
+			if corrupt, err := server.scrubFile("video/file"); corrupt || err != nil {
+				t.Errorf("scrubFile = %v, %v, want the file left alone", corrupt, err)
+			}
+		})
+	}
+}
+
+// TestS3TombstoneHidesVideo checks that a video whose delete was interrupted
+// after its tombstone was written looks deleted until the delete finishes.
+func TestS3TombstoneHidesVideo(t *testing.T) {
+	backend := testBackends["s3"](t).(*S3Backend)
+	writeFiles(t, backend, "video/a", "video/b", "other/a")
+
+	_, err := backend.client.PutObject(context.Background(), backend.bucket, "video/"+s3Tombstone, strings.NewReader("video"), 5, minio.PutObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := backend.Read("video/a"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Read of a file in a deleted video: %v, want fs.ErrNotExist", err)
+	}
+	if _, _, err := backend.Stat("video/b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat of a file in a deleted video: %v, want fs.ErrNotExist", err)
+	}
+	if got, want := walk(t, backend, "", ""), []string{"other/a"}; !slices.Equal(got, want) {
+		t.Errorf("Walk = %v, want %v", got, want)
+	}
+
+	// Writing to the video again finishes the delete first
+	writeFiles(t, backend, "video/b")
+	if got, want := walk(t, backend, "", ""), []string{"other/a", "video/b"}; !slices.Equal(got, want) {
+		t.Errorf("Walk after writing to the video again = %v, want %v", got, want)
+	}
+
+	// A retried delete removes the tombstone along with the files
+	if _, err := backend.client.PutObject(context.Background(), backend.bucket, "video/"+s3Tombstone, strings.NewReader("video"), 5, minio.PutObjectOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.DeleteVideo("video"); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, err := backend.deleted(context.Background(), "video"); err != nil || deleted {
+		t.Errorf("tombstone left behind by a finished delete: %v", err)
+	}
+	if err := backend.DeleteVideo("video"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("DeleteVideo of a deleted video: %v, want fs.ErrNotExist", err)
+	}
+}
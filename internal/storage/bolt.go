@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+	pb "tritontube/internal/proto"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket holds every file. Keys are "<videoId>\x00<filename>", which sorts
+// files by video id and then filename since names cannot contain control
+// characters. Values are the file's SHA-256 followed by its data.
+var boltBucket = []byte("files")
+
+// BoltBackend stores files in a single bbolt database file, which avoids the
+// per-file overhead of the filesystem for nodes holding many small segments.
+type BoltBackend struct {
+	db *bolt.DB
+}
+
+// OpenBoltBackend opens the database at path, creating it if needed.
+func OpenBoltBackend(path string) (*BoltBackend, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltBackend{db: db}, nil
+}
+
+func boltKey(fileId string) []byte {
+	videoId, filename, _ := strings.Cut(fileId, "/")
+	return []byte(videoId + "\x00" + filename)
+}
+
+func boltFileId(key []byte) string {
+	videoId, filename, _ := strings.Cut(string(key), "\x00")
+	return videoId + "/" + filename
+}
+
+// boltGet looks up a file in tx. The returned slices are only valid until tx ends.
+func boltGet(tx *bolt.Tx, fileId string) ([]byte, []byte, error) {
+	value := tx.Bucket(boltBucket).Get(boltKey(fileId))
+	if value == nil {
+		return nil, nil, fmt.Errorf("%s: %w", fileId, fs.ErrNotExist)
+	}
+	if len(value) < sha256.Size {
+		return nil, nil, fmt.Errorf("%s: stored value is too short", fileId)
+	}
+	return value[sha256.Size:], value[:sha256.Size], nil
+}
+
+func (b *BoltBackend) Read(fileId string) ([]byte, []byte, error) {
+	var data, checksum []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		storedData, storedChecksum, err := boltGet(tx, fileId)
+		if err != nil {
+			return err
+		}
+		data, checksum = bytes.Clone(storedData), bytes.Clone(storedChecksum)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, checksum, nil
+}
+
+func (b *BoltBackend) Write(fileId string, data []byte, checksum []byte) error {
+	if len(checksum) != sha256.Size {
+		return fmt.Errorf("%s: checksum must be %d bytes", fileId, sha256.Size)
+	}
+
+	value := make([]byte, 0, len(checksum)+len(data))
+	value = append(value, checksum...)
+	value = append(value, data...)
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(boltKey(fileId), value)
+	})
+}
+
+func (b *BoltBackend) Stat(fileId string) (int64, []byte, error) {
+	var size int64
+	var checksum []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		storedData, storedChecksum, err := boltGet(tx, fileId)
+		if err != nil {
+			return err
+		}
+		size, checksum = int64(len(storedData)), bytes.Clone(storedChecksum)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return size, checksum, nil
+}
+
+func (b *BoltBackend) Delete(fileId string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		_, _, err := boltGet(tx, fileId)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(boltBucket).Delete(boltKey(fileId))
+	})
+}
+
+// DeleteVideo removes all of a video's files in a single transaction.
+func (b *BoltBackend) DeleteVideo(videoId string) error {
+	prefix := []byte(videoId + "\x00")
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		// Deleting while iterating makes the cursor skip keys, so collect
+		// the keys first
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			keys = append(keys, bytes.Clone(key))
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("video %s: %w", videoId, fs.ErrNotExist)
+		}
+
+		for _, key := range keys {
+			err := bucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Walk visits files in order of video id and then filename. It runs inside a
+// read transaction, so fn must not write to the backend.
+func (b *BoltBackend) Walk(videoIdPrefix string, after string, fn func(file *pb.FileInfo) error) error {
+	prefix := []byte(videoIdPrefix)
+	start := prefix
+	var afterKey []byte
+	if after != "" {
+		afterKey = boltKey(after)
+		if bytes.Compare(afterKey, start) > 0 {
+			start = afterKey
+		}
+	}
+
+	return b.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltBucket).Cursor()
+		for key, value := cursor.Seek(start); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			if afterKey != nil && bytes.Equal(key, afterKey) {
+				continue
+			}
+
+			err := fn(&pb.FileInfo{FileId: boltFileId(key), Size: int64(len(value) - sha256.Size)})
+			if err == fs.SkipAll {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
+
+func (b *BoltBackend) BytesFree() (int64, error) {
+	return diskFree(filepath.Dir(b.db.Path()))
+}
+
+var _ Backend = (*BoltBackend)(nil)
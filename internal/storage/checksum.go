@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"tritontube/internal/atomicfile"
+	"tritontube/internal/fileid"
+)
+
+// checksumPrefix starts the name of the sidecar file in which FSBackend keeps
+// the SHA-256 of each stored file, next to it in the same video directory.
+const checksumPrefix = ".sha256-"
+
+// errChecksumMismatch is returned when data does not match its checksum.
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+func checksumName(fileId string) string {
+	videoId, filename, _ := strings.Cut(fileId, "/")
+	return path.Join(videoId, checksumPrefix+filename)
+}
+
+// readChecksums returns the recorded checksums of a file, or an error
+// wrapping fs.ErrNotExist if none were recorded. There is more than one only
+// if a write was interrupted, in which case the data matches one of them.
+func readChecksums(root *os.Root, fileId string) ([][]byte, error) {
+	file, err := root.Open(checksumName(fileId))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	encoded, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var checksums [][]byte
+	for _, line := range strings.Fields(string(encoded)) {
+		checksum, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, err
+		}
+		checksums = append(checksums, checksum)
+	}
+	if len(checksums) == 0 {
+		return nil, fmt.Errorf("%s: empty checksum file", fileId)
+	}
+	return checksums, nil
+}
+
+// matchChecksum picks the checksum data matches out of those recorded. If it
+// matches none, the data is corrupt and the newest checksum is returned so
+// that readers reject it.
+func matchChecksum(data []byte, checksums [][]byte) []byte {
+	if len(checksums) == 1 {
+		return checksums[0]
+	}
+	sum := sha256.Sum256(data)
+	for _, checksum := range checksums {
+		if bytes.Equal(checksum, sum[:]) {
+			return checksum
+		}
+	}
+	return checksums[0]
+}
+
+func writeChecksums(root *os.Root, fileId string, checksums ...[]byte) error {
+	var encoded strings.Builder
+	for _, checksum := range checksums {
+		encoded.WriteString(hex.EncodeToString(checksum) + "\n")
+	}
+	return atomicfile.WriteFile(root, checksumName(fileId), []byte(encoded.String()), 0644)
+}
+
+// statFile returns the size and checksum of a stored file. Files written
+// before checksums were recorded are hashed on the fly.
+func (s *NetworkVideoContentServer) statFile(fileId string) (int64, []byte, error) {
+	_, _, err := fileid.Split(fileId)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	unlock := s.lockFile(fileId, false)
+	defer unlock()
+
+	size, checksum, err := s.backend().Stat(fileId)
+	if err != nil {
+		return 0, nil, err
+	}
+	if checksum == nil {
+		data, _, err := s.backend().Read(fileId)
+		if err != nil {
+			return 0, nil, err
+		}
+		sum := sha256.Sum256(data)
+		size, checksum = int64(len(data)), sum[:]
+	}
+
+	return size, checksum, nil
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+	"tritontube/internal/fileid"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{nil, codes.OK},
+		{fmt.Errorf("%w: ..", fileid.ErrInvalid), codes.InvalidArgument},
+		{&fs.PathError{Op: "open", Path: "video/manifest.mpd", Err: fs.ErrNotExist}, codes.NotFound},
+		{fmt.Errorf("writing video/manifest.mpd: %w", errQuotaExceeded), codes.ResourceExhausted},
+		{&fs.PathError{Op: "write", Path: "video/manifest.mpd", Err: syscall.ENOSPC}, codes.ResourceExhausted},
+		{&fs.PathError{Op: "write", Path: "video/manifest.mpd", Err: syscall.EDQUOT}, codes.ResourceExhausted},
+		{fmt.Errorf("video/manifest.mpd: %w", errChecksumMismatch), codes.DataLoss},
+		{context.Canceled, codes.Canceled},
+		{fmt.Errorf("reading: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{status.Error(codes.Unavailable, "node down"), codes.Unavailable},
+		{&fs.PathError{Op: "open", Path: "video/manifest.mpd", Err: syscall.EIO}, codes.Internal},
+	}
+
+	for _, test := range tests {
+		if got := status.Code(statusError(test.err)); got != test.want {
+			t.Errorf("statusError(%v) has code %v, want %v", test.err, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"syscall"
+	"tritontube/internal/atomicfile"
+	"tritontube/internal/fileid"
+	pb "tritontube/internal/proto"
+)
+
+// FSBackend stores files in a local directory, one subdirectory per video,
+// with the checksum of each file in a hidden sidecar next to it. While a write
+// is replacing a file, the sidecar holds the checksums of both versions.
+type FSBackend struct {
+	Dir string
+}
+
+// openRoot opens the base directory so that file ids are resolved inside it
+// and can never reach outside it, even through symlinks.
+func (b FSBackend) openRoot() (*os.Root, error) {
+	return os.OpenRoot(b.Dir)
+}
+
+func (b FSBackend) Read(fileId string) ([]byte, []byte, error) {
+	root, err := b.openRoot()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer root.Close()
+
+	file, err := root.Open(fileId)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	checksums, err := readChecksums(root, fileId)
+	if errors.Is(err, fs.ErrNotExist) {
+		return data, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return data, matchChecksum(data, checksums), nil
+}
+
+func (b FSBackend) Write(fileId string, data []byte, checksum []byte) error {
+	videoId, _, _ := strings.Cut(fileId, "/")
+
+	root, err := b.openRoot()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	err = root.Mkdir(videoId, 0755)
+	if err == nil {
+		err = atomicfile.SyncDir(root, ".")
+	}
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		slog.Error("Error while creating directory", "video_id", videoId, "err", err)
+		return err
+	}
+
+	// Record the new checksum alongside the old ones before replacing the
+	// data, so that whichever version a crash leaves behind has its checksum.
+	// New files need not be, as a crash leaves them with no checksum at all.
+	old, err := readChecksums(root, fileId)
+	if err == nil {
+		err = writeChecksums(root, fileId, append([][]byte{checksum}, old...)...)
+		if err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("Discarding unreadable checksum", append(fileFields(fileId), "err", err)...)
+	}
+
+	// Write to a temporary file and rename it into place, so that a crash
+	// mid-write never leaves a truncated file to be served later
+	err = atomicfile.WriteFile(root, fileId, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return writeChecksums(root, fileId, checksum)
+}
+
+func (b FSBackend) Stat(fileId string) (int64, []byte, error) {
+	root, err := b.openRoot()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer root.Close()
+
+	info, err := root.Stat(fileId)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	checksums, err := readChecksums(root, fileId)
+	if errors.Is(err, fs.ErrNotExist) {
+		return info.Size(), nil, nil
+	} else if err != nil {
+		return 0, nil, err
+	}
+	if len(checksums) > 1 {
+		// A write was interrupted, so find out which version is left
+		data, err := fs.ReadFile(root.FS(), fileId)
+		if err != nil {
+			return 0, nil, err
+		}
+		return int64(len(data)), matchChecksum(data, checksums), nil
+	}
+
+	return info.Size(), checksums[0], nil
+}
+
+func (b FSBackend) Delete(fileId string) error {
+	root, err := b.openRoot()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	err = root.Remove(fileId)
+	if err != nil {
+		return err
+	}
+
+	err = root.Remove(checksumName(fileId))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeleteVideo renames the video directory out of the way before removing it,
+// so that readers see either the whole video or none of it.
+func (b FSBackend) DeleteVideo(videoId string) error {
+	root, err := b.openRoot()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	return atomicfile.RemoveDir(root, videoId)
+}
+
+// Walk visits files in order of video id and then filename.
+func (b FSBackend) Walk(videoIdPrefix string, after string, fn func(file *pb.FileInfo) error) error {
+	var afterVideo, afterFile string
+	if after != "" {
+		var err error
+		afterVideo, afterFile, err = fileid.Split(after)
+		if err != nil {
+			return err
+		}
+	}
+
+	root, err := b.openRoot()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	videos, err := fs.ReadDir(root.FS(), ".")
+	if err != nil {
+		slog.Error("Error while reading directory", "err", err)
+		return err
+	}
+
+	for _, video := range videos {
+		// Skip anything that could not have been written through Write
+		if !video.IsDir() || fileid.ValidateName(video.Name()) != nil {
+			continue
+		}
+		if !strings.HasPrefix(video.Name(), videoIdPrefix) || video.Name() < afterVideo {
+			continue
+		}
+
+		files, err := fs.ReadDir(root.FS(), video.Name())
+		if errors.Is(err, fs.ErrNotExist) {
+			// Deleted since the walk started
+			continue
+		} else if err != nil {
+			slog.Error("Error while reading directory", "video_id", video.Name(), "err", err)
+			return err
+		}
+
+		for _, file := range files {
+			if !file.Type().IsRegular() || fileid.ValidateName(file.Name()) != nil {
+				continue
+			}
+			if video.Name() == afterVideo && file.Name() <= afterFile {
+				continue
+			}
+
+			info, err := file.Info()
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				slog.Error("Error while reading file info", "video_id", video.Name(), "file", file.Name(), "err", err)
+				return err
+			}
+
+			err = fn(&pb.FileInfo{FileId: fileid.Join(video.Name(), file.Name()), Size: info.Size()})
+			if err == fs.SkipAll {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b FSBackend) Close() error {
+	return nil
+}
+
+// CleanTemp removes temporary files left behind by writes and deletes that
+// were interrupted by a crash.
+func (b FSBackend) CleanTemp() (int, error) {
+	root, err := b.openRoot()
+	if err != nil {
+		return 0, err
+	}
+	defer root.Close()
+
+	return atomicfile.CleanTemp(root)
+}
+
+func (b FSBackend) BytesFree() (int64, error) {
+	return diskFree(b.Dir)
+}
+
+func (b FSBackend) SetChecksum(fileId string, checksum []byte) error {
+	root, err := b.openRoot()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	return writeChecksums(root, fileId, checksum)
+}
+
+// diskFree returns the space left for unprivileged users on the filesystem
+// holding path.
+func diskFree(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, fmt.Errorf("reading disk usage: %w", err)
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+var _ Backend = FSBackend{}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	bytesRead = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tritontube_storage_bytes_read_total",
+		Help: "Bytes of content read by clients.",
+	})
+
+	bytesWritten = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tritontube_storage_bytes_written_total",
+		Help: "Bytes of content written by clients.",
+	})
+
+	bytesFreeDesc = prometheus.NewDesc("tritontube_storage_bytes_free",
+		"Space left where content is stored.", nil, nil)
+	quotaBytesDesc = prometheus.NewDesc("tritontube_storage_quota_bytes",
+		"Most content the node will store, if it has a quota.", nil, nil)
+	quotaUsedBytesDesc = prometheus.NewDesc("tritontube_storage_quota_used_bytes",
+		"Content counted against the quota, if the node has one.", nil, nil)
+	fullDesc = prometheus.NewDesc("tritontube_storage_full",
+		"Whether the node is rejecting writes for lack of space.", nil, nil)
+)
+
+// capacityCollector reports a node's disk usage when scraped.
+type capacityCollector struct {
+	server *NetworkVideoContentServer
+}
+
+// MetricsCollector reports the server's disk usage to Prometheus.
+func (s *NetworkVideoContentServer) MetricsCollector() prometheus.Collector {
+	return capacityCollector{server: s}
+}
+
+func (c capacityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bytesFreeDesc
+	ch <- quotaBytesDesc
+	ch <- quotaUsedBytesDesc
+	ch <- fullDesc
+}
+
+func (c capacityCollector) Collect(ch chan<- prometheus.Metric) {
+	capacity, err := c.server.capacity()
+	if err != nil {
+		slog.Error("Error while reading disk usage", "err", err)
+		return
+	}
+
+	if _, ok := c.server.backend().(spaceReporter); ok {
+		ch <- prometheus.MustNewConstMetric(bytesFreeDesc, prometheus.GaugeValue, float64(capacity.GetBytesFree()))
+	}
+	if capacity.GetQuotaBytes() > 0 {
+		ch <- prometheus.MustNewConstMetric(quotaBytesDesc, prometheus.GaugeValue, float64(capacity.GetQuotaBytes()))
+		ch <- prometheus.MustNewConstMetric(quotaUsedBytesDesc, prometheus.GaugeValue, float64(capacity.GetQuotaBytesUsed()))
+	}
+	full := 0.0
+	if capacity.GetFull() {
+		full = 1
+	}
+	ch <- prometheus.MustNewConstMetric(fullDesc, prometheus.GaugeValue, full)
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsCollectorReportsUsage(t *testing.T) {
+	server := &NetworkVideoContentServer{Dir: t.TempDir(), QuotaBytes: 1000}
+	if err := server.writeFile("video/file", []byte("twelve bytes"), nil); err != nil {
+		t.Fatal(err)
+	}
+	collector := server.MetricsCollector()
+
+	// Free space depends on the disk, so only check that it is reported
+	if count := testutil.CollectAndCount(collector, "tritontube_storage_bytes_free"); count != 1 {
+		t.Errorf("reported free space %d times, want once", count)
+	}
+	expected := `
+# HELP tritontube_storage_full Whether the node is rejecting writes for lack of space.
+# TYPE tritontube_storage_full gauge
+tritontube_storage_full 0
+# HELP tritontube_storage_quota_bytes Most content the node will store, if it has a quota.
+# TYPE tritontube_storage_quota_bytes gauge
+tritontube_storage_quota_bytes 1000
+# HELP tritontube_storage_quota_used_bytes Content counted against the quota, if the node has one.
+# TYPE tritontube_storage_quota_used_bytes gauge
+tritontube_storage_quota_used_bytes 12
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"tritontube_storage_full", "tritontube_storage_quota_bytes", "tritontube_storage_quota_used_bytes")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Without a quota, only free space and fullness are reported
+	server.QuotaBytes = 0
+	if count := testutil.CollectAndCount(collector); count != 2 {
+		t.Errorf("reported %d metrics without a quota, want 2", count)
+	}
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	pb "tritontube/internal/proto"
+)
+
+// errQuotaExceeded is returned for writes that would take a node over its
+// quota.
+var errQuotaExceeded = errors.New("storage quota exceeded")
+
+// minFreeBytes is the room below which a node reports itself full, so that
+// writers move on to other nodes before writes start failing.
+const minFreeBytes = 16 << 20
+
+// loadUsage counts the bytes stored on the node the first time it is needed.
+// The caller must hold s.usageMu.
+func (s *NetworkVideoContentServer) loadUsage() error {
+	if s.usageLoaded {
+		return nil
+	}
+
+	var used int64
+	err := s.walkFiles(listFilter{}, func(file *pb.FileInfo) error {
+		used += file.GetSize()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.usedBytes = used
+	s.usageLoaded = true
+	return nil
+}
+
+// reserveSpace accounts for a file of the given size about to be written,
+// rejecting the write if it would take the node over QuotaBytes. It returns
+// the change in usage, to be handed to adjustUsage if the write fails. The
+// caller must hold the file's lock exclusively.
+func (s *NetworkVideoContentServer) reserveSpace(fileId string, size int64) (int64, error) {
+	if s.QuotaBytes <= 0 {
+		return 0, nil
+	}
+
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	err := s.loadUsage()
+	if err != nil {
+		return 0, err
+	}
+
+	// Overwriting a file only needs room for the difference. Stat under
+	// usageMu, so that the size counted is the one loadUsage saw.
+	oldSize, _, err := s.backend().Stat(fileId)
+	if errors.Is(err, fs.ErrNotExist) {
+		oldSize = 0
+	} else if err != nil {
+		return 0, err
+	}
+	delta := size - oldSize
+
+	if delta > 0 && s.usedBytes+delta > s.QuotaBytes {
+		return 0, fmt.Errorf("%w: writing %s needs %d bytes, %d of %d are in use", errQuotaExceeded, fileId, delta, s.usedBytes, s.QuotaBytes)
+	}
+
+	s.usedBytes += delta
+	return delta, nil
+}
+
+// releaseSpace deletes a file and stops counting its size. It holds usageMu
+// throughout, so that usage is never counted with the file gone but its size
+// not yet subtracted. The caller must hold the file's lock exclusively.
+func (s *NetworkVideoContentServer) releaseSpace(fileId string) error {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	err := s.loadUsage()
+	if err != nil {
+		return err
+	}
+
+	size, _, err := s.backend().Stat(fileId)
+	if err != nil {
+		return err
+	}
+	err = s.backend().Delete(fileId)
+	if err != nil {
+		return err
+	}
+
+	s.usedBytes -= size
+	return nil
+}
+
+// adjustUsage records a change in the bytes stored on the node.
+func (s *NetworkVideoContentServer) adjustUsage(delta int64) {
+	if s.QuotaBytes <= 0 {
+		return
+	}
+
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	if s.usageLoaded {
+		s.usedBytes += delta
+	}
+}
+
+func (s *NetworkVideoContentServer) Capacity(ctx context.Context, req *pb.CapacityRequest) (*pb.CapacityResponse, error) {
+	response, err := s.capacity()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while reading disk usage", "err", err)
+		return nil, statusError(err)
+	}
+
+	return response, nil
+}
+
+// capacity reports how much room the node has left, without walking every
+// file once usage has been counted.
+func (s *NetworkVideoContentServer) capacity() (*pb.CapacityResponse, error) {
+	response := &pb.CapacityResponse{QuotaBytes: s.QuotaBytes}
+
+	reporter, hasFreeSpace := s.backend().(spaceReporter)
+	if hasFreeSpace {
+		bytesFree, err := reporter.BytesFree()
+		if err != nil {
+			return nil, err
+		}
+		response.BytesFree = bytesFree
+		if bytesFree < minFreeBytes {
+			response.Full = true
+		}
+	}
+
+	if s.QuotaBytes > 0 {
+		s.usageMu.Lock()
+		err := s.loadUsage()
+		response.QuotaBytesUsed = s.usedBytes
+		s.usageMu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		// Small quotas get a proportionally smaller margin
+		if s.QuotaBytes-response.QuotaBytesUsed < min(minFreeBytes, s.QuotaBytes/10) {
+			response.Full = true
+		}
+	}
+
+	return response, nil
+}
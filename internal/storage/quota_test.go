@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	pb "tritontube/internal/proto"
+)
+
+// checkUsage fails the test unless the usage the server counted matches the
+// files stored.
+func checkUsage(t *testing.T, server *NetworkVideoContentServer) {
+	t.Helper()
+	capacity, err := server.capacity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored int64
+	err = server.walkFiles(listFilter{}, func(file *pb.FileInfo) error {
+		stored += file.GetSize()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capacity.GetQuotaBytesUsed() != stored {
+		t.Errorf("usage counted as %d bytes, but %d are stored", capacity.GetQuotaBytesUsed(), stored)
+	}
+}
+
+func TestConcurrentWritesCountUsageOnce(t *testing.T) {
+	server := &NetworkVideoContentServer{Dir: t.TempDir(), QuotaBytes: 1 << 20}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fileId := []string{"video/a", "video/b"}[i%2]
+			if _, err := server.Write(ctx, &pb.WriteRequest{FileId: fileId, Data: make([]byte, 1000+i)}); err != nil {
+				t.Error(err)
+			}
+			if i%5 == 0 {
+				server.Delete(ctx, &pb.DeleteRequest{FileId: fileId})
+			}
+		}()
+	}
+	wg.Wait()
+
+	checkUsage(t, server)
+}
+
+func TestDeleteVideoDuringWritesCountsUsage(t *testing.T) {
+	server := &NetworkVideoContentServer{Dir: t.TempDir(), QuotaBytes: 1 << 20}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%7 == 0 {
+				server.DeleteVideo(ctx, &pb.DeleteVideoRequest{VideoId: "video"})
+				return
+			}
+			fileId := []string{"video/a", "video/b", "video/c"}[i%3]
+			if _, err := server.Write(ctx, &pb.WriteRequest{FileId: fileId, Data: make([]byte, 1000+i)}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	checkUsage(t, server)
+}
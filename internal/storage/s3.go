@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	"tritontube/internal/fileid"
+	pb "tritontube/internal/proto"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// s3ChecksumKey is the object metadata key holding a file's hex SHA-256.
+const s3ChecksumKey = "Sha256"
+
+// s3Tombstone names the object that hides a video while it is being deleted.
+// It starts with a dot, so it can never be the name of a file.
+const s3Tombstone = ".deleted"
+
+// S3Options configures the connection to an S3-compatible object store.
+type S3Options struct {
+	// Endpoint is the host and port of the object store, without a scheme.
+	Endpoint  string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Region    string
+	// Secure connects over HTTPS.
+	Secure bool
+}
+
+// S3Backend stores files as objects in a bucket of an S3-compatible object
+// store, keyed by file id, with their checksums in the object metadata.
+type S3Backend struct {
+	client *minio.Client
+	bucket string
+}
+
+// OpenS3Backend connects to the object store and creates the bucket if it
+// does not exist yet.
+func OpenS3Backend(options S3Options) (*S3Backend, error) {
+	client, err := minio.New(options.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(options.AccessKey, options.SecretKey, ""),
+		Secure: options.Secure,
+		Region: options.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, options.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("checking bucket %s: %w", options.Bucket, err)
+	}
+	if !exists {
+		err = client.MakeBucket(ctx, options.Bucket, minio.MakeBucketOptions{Region: options.Region})
+		if err != nil {
+			return nil, fmt.Errorf("creating bucket %s: %w", options.Bucket, err)
+		}
+	}
+
+	return &S3Backend{client: client, bucket: options.Bucket}, nil
+}
+
+// s3Error makes missing objects recognisable as fs.ErrNotExist.
+func s3Error(name string, err error) error {
+	if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+		return fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return err
+}
+
+// deleted reports whether a video has a tombstone, in which case its files
+// are treated as gone.
+func (b *S3Backend) deleted(ctx context.Context, videoId string) (bool, error) {
+	_, err := b.client.StatObject(ctx, b.bucket, path.Join(videoId, s3Tombstone), minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkDeleted returns an error wrapping fs.ErrNotExist if a file's video has
+// a tombstone.
+func (b *S3Backend) checkDeleted(fileId string) error {
+	videoId, _, _ := strings.Cut(fileId, "/")
+	deleted, err := b.deleted(context.Background(), videoId)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		return fmt.Errorf("%s: %w", fileId, fs.ErrNotExist)
+	}
+	return nil
+}
+
+func s3Checksum(info minio.ObjectInfo) ([]byte, error) {
+	encoded, ok := info.UserMetadata[s3ChecksumKey]
+	if !ok {
+		return nil, nil
+	}
+	return hex.DecodeString(encoded)
+}
+
+func (b *S3Backend) Read(fileId string) ([]byte, []byte, error) {
+	err := b.checkDeleted(fileId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	object, err := b.client.GetObject(context.Background(), b.bucket, fileId, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, s3Error(fileId, err)
+	}
+	defer object.Close()
+
+	info, err := object.Stat()
+	if err != nil {
+		return nil, nil, s3Error(fileId, err)
+	}
+	checksum, err := s3Checksum(info)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, nil, s3Error(fileId, err)
+	}
+
+	return data, checksum, nil
+}
+
+// Write relies on the object store to make each object visible only once it
+// has been uploaded completely. Writing to a video with a tombstone first
+// finishes deleting it, so that the new file is not hidden along with the old.
+func (b *S3Backend) Write(fileId string, data []byte, checksum []byte) error {
+	videoId, _, _ := strings.Cut(fileId, "/")
+	deleted, err := b.deleted(context.Background(), videoId)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		slog.Info("Finishing interrupted delete", "video_id", videoId)
+		err = b.DeleteVideo(videoId)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = b.client.PutObject(context.Background(), b.bucket, fileId, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType:  "application/octet-stream",
+		UserMetadata: map[string]string{s3ChecksumKey: hex.EncodeToString(checksum)},
+	})
+	return err
+}
+
+func (b *S3Backend) Stat(fileId string) (int64, []byte, error) {
+	err := b.checkDeleted(fileId)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	info, err := b.client.StatObject(context.Background(), b.bucket, fileId, minio.StatObjectOptions{})
+	if err != nil {
+		return 0, nil, s3Error(fileId, err)
+	}
+
+	checksum, err := s3Checksum(info)
+	if err != nil {
+		return 0, nil, err
+	}
+	return info.Size, checksum, nil
+}
+
+func (b *S3Backend) Delete(fileId string) error {
+	err := b.checkDeleted(fileId)
+	if err != nil {
+		return err
+	}
+
+	// Deleting a missing object succeeds, so check for it first
+	_, err = b.client.StatObject(context.Background(), b.bucket, fileId, minio.StatObjectOptions{})
+	if err != nil {
+		return s3Error(fileId, err)
+	}
+
+	return b.client.RemoveObject(context.Background(), b.bucket, fileId, minio.RemoveObjectOptions{})
+}
+
+// DeleteVideo hides a video behind a tombstone, then removes its objects in
+// bulk and finally the tombstone. Object stores cannot delete several objects
+// atomically, so if removal fails part way the tombstone keeps what is left
+// hidden until the delete is retried or the video is written to again.
+func (b *S3Backend) DeleteVideo(videoId string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tombstone := path.Join(videoId, s3Tombstone)
+	var objects []minio.ObjectInfo
+	var deleted bool
+	for object := range b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{Prefix: videoId + "/", Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if object.Key == tombstone {
+			deleted = true
+			continue
+		}
+		objects = append(objects, object)
+	}
+	if len(objects) == 0 && !deleted {
+		return fmt.Errorf("video %s: %w", videoId, fs.ErrNotExist)
+	}
+
+	if !deleted {
+		// Some S3 stand-ins reject empty objects, so the tombstone names the video
+		_, err := b.client.PutObject(ctx, b.bucket, tombstone, strings.NewReader(videoId), int64(len(videoId)), minio.PutObjectOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	objectsCh := make(chan minio.ObjectInfo, len(objects))
+	for _, object := range objects {
+		objectsCh <- object
+	}
+	close(objectsCh)
+
+	var errs []error
+	for removeErr := range b.client.RemoveObjects(ctx, b.bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		errs = append(errs, fmt.Errorf("%s: %w", removeErr.ObjectName, removeErr.Err))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return b.client.RemoveObject(ctx, b.bucket, tombstone, minio.RemoveObjectOptions{})
+}
+
+// Walk visits files in the lexical order of their file ids, skipping videos
+// with a tombstone.
+func (b *S3Backend) Walk(videoIdPrefix string, after string, fn func(file *pb.FileInfo) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	objects := b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{
+		Prefix:     videoIdPrefix,
+		Recursive:  true,
+		StartAfter: after,
+	})
+	var videoId string
+	var deleted bool
+	for object := range objects {
+		if object.Err != nil {
+			return object.Err
+		}
+		// Skip anything that could not have been written through Write
+		objectVideoId, _, err := fileid.Split(object.Key)
+		if err != nil {
+			continue
+		}
+
+		// Objects come grouped by video, so look for each tombstone once
+		if objectVideoId != videoId {
+			videoId = objectVideoId
+			deleted, err = b.deleted(ctx, videoId)
+			if err != nil {
+				return err
+			}
+		}
+		if deleted {
+			continue
+		}
+
+		err = fn(&pb.FileInfo{FileId: object.Key, Size: object.Size})
+		if err == fs.SkipAll {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *S3Backend) Close() error {
+	return nil
+}
+
+var _ Backend = (*S3Backend)(nil)
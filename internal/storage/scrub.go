@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"tritontube/internal/atomicfile"
+	pb "tritontube/internal/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// defaultQuarantineDir is used under Dir when QuarantineDir is not set. Its
+// name starts with a dot, so it is never mistaken for a video.
+const defaultQuarantineDir = ".quarantine"
+
+// maxQuarantinedFileIds is how many of the most recently quarantined files
+// ScrubStatus lists. CorruptFileCount counts them all.
+const maxQuarantinedFileIds = 100
+
+func (s *NetworkVideoContentServer) quarantineDir() string {
+	if s.QuarantineDir != "" {
+		return s.QuarantineDir
+	}
+	return filepath.Join(s.Dir, defaultQuarantineDir)
+}
+
+// RunScrubber verifies every stored file against its recorded checksum once
+// per interval until ctx is cancelled. Corrupt files are moved to the
+// quarantine directory so that they are no longer served.
+func (s *NetworkVideoContentServer) RunScrubber(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.scrub(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Error while scrubbing", "err", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *NetworkVideoContentServer) ScrubStatus(ctx context.Context, req *pb.ScrubStatusRequest) (*pb.ScrubStatusResponse, error) {
+	s.scrubMu.Lock()
+	defer s.scrubMu.Unlock()
+
+	if s.scrubStatus == nil {
+		return &pb.ScrubStatusResponse{}, nil
+	}
+	return proto.Clone(s.scrubStatus).(*pb.ScrubStatusResponse), nil
+}
+
+// scrub makes a single pass over every stored file, reading no more than
+// ScrubBytesPerSecond so that it does not compete with serving.
+func (s *NetworkVideoContentServer) scrub(ctx context.Context) error {
+	var files []*pb.FileInfo
+	err := s.walkFiles(listFilter{}, func(file *pb.FileInfo) error {
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.scrubMu.Lock()
+	if s.scrubStatus == nil {
+		s.scrubStatus = &pb.ScrubStatusResponse{}
+	}
+	s.scrubStatus.Running = true
+	s.scrubStatus.LastPassStartedUnix = time.Now().Unix()
+	s.scrubStatus.LastPassFileCount = 0
+	s.scrubStatus.LastPassByteCount = 0
+	s.scrubStatus.LastPassCorruptFileCount = 0
+	s.scrubMu.Unlock()
+
+	defer func() {
+		s.scrubMu.Lock()
+		s.scrubStatus.Running = false
+		s.scrubMu.Unlock()
+	}()
+
+	limiter := &rateLimiter{rate: s.ScrubBytesPerSecond}
+	for _, file := range files {
+		err := limiter.wait(ctx, int(file.GetSize()))
+		if err != nil {
+			return err
+		}
+
+		corrupt, err := s.scrubFile(file.GetFileId())
+		if errors.Is(err, fs.ErrNotExist) {
+			// Deleted since the pass started
+			continue
+		} else if err != nil && !corrupt {
+			slog.Error("Error while scrubbing", append(fileFields(file.GetFileId()), "err", err)...)
+			continue
+		}
+
+		s.scrubMu.Lock()
+		s.scrubStatus.LastPassFileCount++
+		s.scrubStatus.LastPassByteCount += file.GetSize()
+		s.scrubMu.Unlock()
+
+		if !corrupt {
+			continue
+		}
+		if err != nil {
+			slog.Error("Error while quarantining", append(fileFields(file.GetFileId()), "err", err)...)
+			continue
+		}
+
+		s.scrubMu.Lock()
+		s.scrubStatus.LastPassCorruptFileCount++
+		s.scrubStatus.CorruptFileCount++
+		s.scrubStatus.QuarantinedFileIds = append(s.scrubStatus.QuarantinedFileIds, file.GetFileId())
+		if extra := len(s.scrubStatus.QuarantinedFileIds) - maxQuarantinedFileIds; extra > 0 {
+			s.scrubStatus.QuarantinedFileIds = slices.Delete(s.scrubStatus.QuarantinedFileIds, 0, extra)
+		}
+		s.scrubMu.Unlock()
+	}
+
+	s.scrubMu.Lock()
+	s.scrubStatus.PassCount++
+	s.scrubStatus.LastPassFinishedUnix = time.Now().Unix()
+	slog.Info("Scrub pass finished", "files", s.scrubStatus.LastPassFileCount,
+		"bytes", s.scrubStatus.LastPassByteCount, "corrupt", s.scrubStatus.LastPassCorruptFileCount)
+	s.scrubMu.Unlock()
+
+	return nil
+}
+
+// scrubFile reports whether a file no longer matches its recorded checksum,
+// moving it to quarantine if so. Files without a recorded checksum get one, if
+// the backend allows it. The file is locked throughout, so a write cannot
+// change it under the scrubber.
+func (s *NetworkVideoContentServer) scrubFile(fileId string) (bool, error) {
+	unlock := s.lockFile(fileId, true)
+	defer unlock()
+
+	data, recorded, err := s.backend().Read(fileId)
+	if err != nil {
+		return false, err
+	}
+	actual := sha256.Sum256(data)
+
+	if recorded == nil {
+		if recorder, ok := s.backend().(checksumRecorder); ok {
+			return false, recorder.SetChecksum(fileId, actual[:])
+		}
+		return false, nil
+	}
+	if bytes.Equal(actual[:], recorded) {
+		return false, nil
+	}
+
+	slog.Warn("Checksum mismatch, moving file to quarantine", fileFields(fileId)...)
+	return true, s.quarantine(fileId, data, recorded)
+}
+
+// quarantine moves a corrupt file and its checksum out of the backend into
+// the quarantine directory. The caller must hold the file's lock exclusively.
+func (s *NetworkVideoContentServer) quarantine(fileId string, data []byte, recorded []byte) error {
+	videoId, filename, _ := strings.Cut(fileId, "/")
+
+	err := os.MkdirAll(filepath.Join(s.quarantineDir(), videoId), 0755)
+	if err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(s.quarantineDir())
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	// Keep the copies whole, as the original is removed once they are made
+	target := path.Join(videoId, fmt.Sprintf("%s.%d", filename, time.Now().Unix()))
+	err = atomicfile.WriteFile(root, target, data, 0644)
+	if err != nil {
+		return err
+	}
+	if recorded != nil {
+		err = atomicfile.WriteFile(root, target+".sha256", []byte(hex.EncodeToString(recorded)+"\n"), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.removeLockedFile(fileId)
+}
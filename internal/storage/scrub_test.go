@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	pb "tritontube/internal/proto"
+)
+
+func TestScrubQuarantinesCorruptFiles(t *testing.T) {
+	base := t.TempDir()
+	server := &NetworkVideoContentServer{Dir: base}
+	ctx := context.Background()
+
+	for _, fileId := range []string{"video/good", "video/bad"} {
+		if _, err := server.Write(ctx, &pb.WriteRequest{FileId: fileId, Data: []byte("data")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(base, "video", "bad"), []byte("rot!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.scrub(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	status, _ := server.ScrubStatus(ctx, &pb.ScrubStatusRequest{})
+	if status.GetCorruptFileCount() != 1 || len(status.GetQuarantinedFileIds()) != 1 || status.GetQuarantinedFileIds()[0] != "video/bad" {
+		t.Errorf("scrub status = %v, want only video/bad quarantined", status)
+	}
+	if _, err := server.Read(ctx, &pb.ReadRequest{FileId: "video/bad"}); err == nil {
+		t.Errorf("corrupt file is still served")
+	}
+	if _, err := server.Read(ctx, &pb.ReadRequest{FileId: "video/good"}); err != nil {
+		t.Errorf("good file is no longer served: %v", err)
+	}
+	quarantined, _ := filepath.Glob(filepath.Join(base, defaultQuarantineDir, "video", "bad.*"))
+	if len(quarantined) != 2 {
+		t.Errorf("quarantine holds %v, want the file and its checksum", quarantined)
+	}
+}
+
+func TestScrubDuringWritesQuarantinesNothing(t *testing.T) {
+	server := &NetworkVideoContentServer{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for _, fileId := range []string{"video/a", "video/b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				data := make([]byte, 1024+i%7)
+				data[0] = byte(i)
+				if _, err := server.Write(ctx, &pb.WriteRequest{FileId: fileId, Data: data}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	for range 20 {
+		if err := server.scrub(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	status, _ := server.ScrubStatus(ctx, &pb.ScrubStatusRequest{})
+	if status.GetCorruptFileCount() != 0 {
+		t.Errorf("scrubbing during writes quarantined %v", status.GetQuarantinedFileIds())
+	}
+}
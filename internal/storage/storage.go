@@ -3,78 +3,375 @@
 package storage
 
 import (
+	"bytes"
 	"context"
-	"log"
-	"os"
-	"path"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"strings"
+	"sync"
+	"time"
+	"tritontube/internal/fileid"
+	"tritontube/internal/hashring"
 	pb "tritontube/internal/proto"
+	"tritontube/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
+// Version identifies the storage server build. It can be set at link time
+// with -ldflags "-X tritontube/internal/storage.Version=...".
+var Version = "dev"
+
 // Implement a network video content service (server)
 type NetworkVideoContentServer struct {
 	pb.UnimplementedNetworkVideoContentServer
 	Dir string
+	// Backend holds the stored files. It defaults to the local filesystem
+	// under Dir.
+	Backend Backend
+	// StartedAt is when the server started, used to report uptime.
+	StartedAt time.Time
+	// QuarantineDir is a local directory that receives files the scrubber
+	// finds to be corrupt. It defaults to a hidden directory inside Dir.
+	QuarantineDir string
+	// ScrubBytesPerSecond caps how fast the scrubber reads. Zero means
+	// unlimited.
+	ScrubBytesPerSecond int64
+	// QuotaBytes caps how much content the node stores. Zero means no quota
+	// beyond the space the backend has.
+	QuotaBytes int64
+	// ClientCredentials secure connections to other storage nodes during
+	// transfers. They default to plaintext.
+	ClientCredentials credentials.TransportCredentials
+	// TLSEnabled is set when ClientCredentials only accept nodes with a
+	// certificate from the cluster CA, in which case files may be transferred
+	// to any such node.
+	TLSEnabled bool
+	// TransferPeers lists the addresses files may be transferred to without
+	// TLS, or "*" for any.
+	TransferPeers []string
+
+	scrubMu sync.Mutex
+	scrubStatus *pb.ScrubStatusResponse
+
+	// usageMu guards usedBytes, which is only tracked when there is a quota.
+	usageMu sync.Mutex
+	usageLoaded bool
+	usedBytes int64
+
+	// locksMu guards locks, which holds the lock of every file and video
+	// being used, keyed by file id or video id.
+	locksMu sync.Mutex
+	locks map[string]*fileLock
 }
 
-func (s *NetworkVideoContentServer) Read(ctx context.Context, readRequest *pb.ReadRequest) (*pb.ReadResponse, error) {
-	readData, err := os.ReadFile(path.Join(s.Dir, readRequest.GetFileId()))
-	if os.IsNotExist(err) {
-		return nil, nil
-	} else if err != nil {
-		log.Printf("Error while reading from file: %v", err)
-		return nil, err
+// fileLock serializes changes to a file or video. refs counts the callers
+// holding or waiting for it, so that it can be dropped once nobody needs it.
+type fileLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// lock takes the lock for a file or video id, returning the function that
+// releases it.
+func (s *NetworkVideoContentServer) lock(id string, exclusive bool) func() {
+	s.locksMu.Lock()
+	if s.locks == nil {
+		s.locks = make(map[string]*fileLock)
+	}
+	lock := s.locks[id]
+	if lock == nil {
+		lock = &fileLock{}
+		s.locks[id] = lock
+	}
+	lock.refs++
+	s.locksMu.Unlock()
+
+	if exclusive {
+		lock.Lock()
+	} else {
+		lock.RLock()
+	}
+
+	return func() {
+		if exclusive {
+			lock.Unlock()
+		} else {
+			lock.RUnlock()
+		}
+
+		s.locksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.locks, id)
+		}
+		s.locksMu.Unlock()
 	}
-	return &pb.ReadResponse{Data: readData}, nil
 }
 
-func (s *NetworkVideoContentServer) Write(ctx context.Context, writeRequest *pb.WriteRequest) (*pb.WriteResponse, error) {
-	dirName := path.Join(s.Dir, strings.Split(writeRequest.GetFileId(), "/")[0])
+// lockFile locks a file, shared if the caller only reads it and exclusively
+// if it changes the file or its checksum. Its video is locked shared as well,
+// so that the video cannot be deleted meanwhile. It returns the function
+// unlocking both.
+func (s *NetworkVideoContentServer) lockFile(fileId string, exclusive bool) func() {
+	videoId, _, _ := strings.Cut(fileId, "/")
+	unlockVideo := s.lock(videoId, false)
+	unlockFile := s.lock(fileId, exclusive)
+	return func() {
+		unlockFile()
+		unlockVideo()
+	}
+}
+
+// lockVideo locks a video exclusively, keeping every file of it from being
+// read or changed.
+func (s *NetworkVideoContentServer) lockVideo(videoId string) func() {
+	return s.lock(videoId, true)
+}
+
+// fileFields are the log fields identifying a file.
+func fileFields(fileId string) []any {
+	videoId, filename, _ := strings.Cut(fileId, "/")
+	return []any{"video_id", videoId, "file", filename}
+}
 
-	err := os.MkdirAll(dirName, 0755)
+// fileAttributes are the trace span attributes identifying a file.
+func fileAttributes(fileId string) []attribute.KeyValue {
+	videoId, filename, _ := strings.Cut(fileId, "/")
+	return []attribute.KeyValue{attribute.String("video_id", videoId), attribute.String("file", filename)}
+}
+
+// readFile returns a file's contents along with its recorded checksum. Files
+// written before checksums were recorded are hashed on the fly.
+func (s *NetworkVideoContentServer) readFile(fileId string) ([]byte, []byte, error) {
+	_, _, err := fileid.Split(fileId)
 	if err != nil {
-		log.Printf("Error while creating directory: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	err = os.WriteFile(path.Join(s.Dir, writeRequest.GetFileId()), writeRequest.GetData(), 0644)
+	unlock := s.lockFile(fileId, false)
+	defer unlock()
+
+	data, checksum, err := s.backend().Read(fileId)
 	if err != nil {
-		log.Printf("Error while writing to file: %v", err)
-		return nil, err
+		return nil, nil, err
+	}
+	if checksum == nil {
+		sum := sha256.Sum256(data)
+		checksum = sum[:]
 	}
 
-	return &pb.WriteResponse{}, nil
+	return data, checksum, nil
 }
 
-func (s *NetworkVideoContentServer) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
-	var file_ids []string
+// writeFile stores a file and records its checksum with it. If checksum is
+// set, the write is rejected unless the data matches it.
+func (s *NetworkVideoContentServer) writeFile(fileId string, data []byte, checksum []byte) error {
+	_, _, err := fileid.Split(fileId)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	if len(checksum) > 0 && !bytes.Equal(checksum, sum[:]) {
+		return fmt.Errorf("%w: data for %s does not match the checksum sent with it", errChecksumMismatch, fileId)
+	}
+
+	unlock := s.lockFile(fileId, true)
+	defer unlock()
+
+	delta, err := s.reserveSpace(fileId, int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	err = s.backend().Write(fileId, data, sum[:])
+	if err != nil {
+		s.adjustUsage(-delta)
+		return err
+	}
+	return nil
+}
 
-	videos, err := os.ReadDir(s.Dir)
+// CleanTempFiles removes temporary files left behind by writes and deletes
+// that were interrupted by a crash. It should be called before the server
+// starts.
+func (s *NetworkVideoContentServer) CleanTempFiles() error {
+	cleaner, ok := s.backend().(tempCleaner)
+	if !ok {
+		return nil
+	}
+
+	removed, err := cleaner.CleanTemp()
+	if removed > 0 {
+		slog.Info("Removed leftover temporary files", "count", removed)
+	}
+	return err
+}
+
+func (s *NetworkVideoContentServer) removeFile(fileId string) error {
+	_, _, err := fileid.Split(fileId)
 	if err != nil {
-		log.Printf("Error while reading directory: %v)", err)
-		return nil, err
+		return err
 	}
 
-	for _, video := range videos {
-		files, err := os.ReadDir(path.Join(s.Dir, video.Name()))
+	unlock := s.lockFile(fileId, true)
+	defer unlock()
+	return s.removeLockedFile(fileId)
+}
+
+// removeLockedFile removes a file the caller has locked exclusively.
+func (s *NetworkVideoContentServer) removeLockedFile(fileId string) error {
+	if s.QuotaBytes > 0 {
+		return s.releaseSpace(fileId)
+	}
+	return s.backend().Delete(fileId)
+}
+
+func (s *NetworkVideoContentServer) Read(ctx context.Context, readRequest *pb.ReadRequest) (*pb.ReadResponse, error) {
+	started := time.Now()
+	_, span := tracing.Start(ctx, "NetworkVideoContentServer.readFile", fileAttributes(readRequest.GetFileId())...)
+	readData, checksum, err := s.readFile(readRequest.GetFileId())
+	tracing.End(span, err)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, status.Errorf(codes.NotFound, "file %s not found", readRequest.GetFileId())
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error while reading from file", append(fileFields(readRequest.GetFileId()), "err", err)...)
+		return nil, statusError(err)
+	}
+	slog.DebugContext(ctx, "Read file", append(fileFields(readRequest.GetFileId()), "duration", time.Since(started))...)
+	bytesRead.Add(float64(len(readData)))
+	return &pb.ReadResponse{Data: readData, Sha256: checksum}, nil
+}
+
+func (s *NetworkVideoContentServer) Write(ctx context.Context, writeRequest *pb.WriteRequest) (*pb.WriteResponse, error) {
+	started := time.Now()
+	_, span := tracing.Start(ctx, "NetworkVideoContentServer.writeFile", fileAttributes(writeRequest.GetFileId())...)
+	err := s.writeFile(writeRequest.GetFileId(), writeRequest.GetData(), writeRequest.GetSha256())
+	tracing.End(span, err)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while writing to file", append(fileFields(writeRequest.GetFileId()), "err", err)...)
+		return nil, statusError(err)
+	}
+	slog.DebugContext(ctx, "Wrote file", append(fileFields(writeRequest.GetFileId()), "duration", time.Since(started))...)
+	bytesWritten.Add(float64(len(writeRequest.GetData())))
+
+	return &pb.WriteResponse{}, nil
+}
+
+// listFilter selects the files walkFiles visits.
+type listFilter struct {
+	videoIdPrefix string
+	// after is the file id to resume after, if set
+	after string
+	hashRange *pb.HashRange
+}
+
+// walkFiles calls fn for every stored file matching filter. Returning
+// fs.SkipAll from fn stops the walk.
+func (s *NetworkVideoContentServer) walkFiles(filter listFilter, fn func(file *pb.FileInfo) error) error {
+	if filter.after != "" {
+		_, _, err := fileid.Split(filter.after)
 		if err != nil {
-			log.Printf("Error while reading directory: %v)", err)
-			return nil, err
+			return err
 		}
+	}
 
-		for _, file := range files {
-			file_ids = append(file_ids, video.Name() + "/" + file.Name())
+	return s.backend().Walk(filter.videoIdPrefix, filter.after, func(file *pb.FileInfo) error {
+		if filter.hashRange != nil && !hashring.InRange(hashring.Hash(file.GetFileId()), filter.hashRange.GetStart(), filter.hashRange.GetEnd()) {
+			return nil
 		}
+		return fn(file)
+	})
+}
+
+const (
+	defaultListPageSize = 1000
+	maxListPageSize = 10000
+)
+
+func (s *NetworkVideoContentServer) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	pageSize := int(req.GetPageSize())
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	} else if pageSize > maxListPageSize {
+		pageSize = maxListPageSize
 	}
 
-	return &pb.ListResponse{FileIds: file_ids}, nil
+	response := &pb.ListResponse{}
+	filter := listFilter{
+		videoIdPrefix: req.GetVideoIdPrefix(),
+		after: req.GetPageToken(),
+		hashRange: req.GetHashRange(),
+	}
+	err := s.walkFiles(filter, func(file *pb.FileInfo) error {
+		// Only hand out a page token if there is something after it
+		if len(response.Files) == pageSize {
+			response.NextPageToken = response.Files[pageSize - 1].GetFileId()
+			return fs.SkipAll
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		response.FileIds = append(response.FileIds, file.GetFileId())
+		response.Files = append(response.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return response, nil
 }
 
 func (s *NetworkVideoContentServer) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
-	err := os.Remove(path.Join(s.Dir, req.GetFileId()))
+	err := s.removeFile(req.GetFileId())
 	if err != nil {
-		return nil, err
+		return nil, statusError(err)
 	}
 
 	return &pb.DeleteResponse{}, nil
-}
\ No newline at end of file
+}
+
+func (s *NetworkVideoContentServer) Stat(ctx context.Context, req *pb.StatRequest) (*pb.StatResponse, error) {
+	size, checksum, err := s.statFile(req.GetFileId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pb.StatResponse{FileId: req.GetFileId(), Size: size, Sha256: checksum}, nil
+}
+
+func (s *NetworkVideoContentServer) Status(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
+	response := &pb.StatusResponse{Version: Version}
+	if !s.StartedAt.IsZero() {
+		response.UptimeSeconds = int64(time.Since(s.StartedAt).Seconds())
+	}
+
+	err := s.walkFiles(listFilter{}, func(file *pb.FileInfo) error {
+		response.FileCount++
+		response.BytesUsed += file.GetSize()
+		return nil
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	capacity, err := s.capacity()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while reading disk usage", "err", err)
+		return nil, statusError(err)
+	}
+	response.BytesFree = capacity.GetBytesFree()
+	response.QuotaBytes = capacity.GetQuotaBytes()
+	response.Full = capacity.GetFull()
+
+	return response, nil
+}
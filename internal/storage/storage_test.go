@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"tritontube/internal/hashring"
+	pb "tritontube/internal/proto"
+)
+
+// escapeSeeds are file ids that try to reach outside the base directory.
+var escapeSeeds = []string{
+	"video/manifest.mpd",
+	"../outside/secret",
+	"video/../../outside/secret",
+	"/etc/passwd",
+	"video//etc/passwd",
+	"..",
+	"video/..",
+	"link/secret",
+	"video/link",
+	"video/file\x00.m4s",
+	"video\x00/file",
+	".hidden/file",
+	"video/.sha256-file",
+}
+
+// escapeTree creates a base directory for content and a directory outside it
+// holding a secret, with symlinks from the base directory to the secret.
+func escapeTree(t *testing.T) (string, string) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(base, "video"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(base, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(base, "video", "link")); err != nil {
+		t.Fatal(err)
+	}
+	return base, outside
+}
+
+// checkOutside fails the test if anything outside the base directory changed.
+func checkOutside(t *testing.T, outside string) {
+	t.Helper()
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "secret" {
+		t.Fatalf("files outside the base directory changed: %v", entries)
+	}
+	data, err := os.ReadFile(filepath.Join(outside, "secret"))
+	if err != nil || string(data) != "secret" {
+		t.Fatalf("secret outside the base directory changed: %q, %v", data, err)
+	}
+}
+
+// unsafeId reports whether a file id is one that must always be rejected.
+func unsafeId(fileId string) bool {
+	return strings.HasPrefix(fileId, "/") || strings.ContainsRune(fileId, 0) || slices.Contains(strings.Split(fileId, "/"), "..")
+}
+
+func FuzzFileId(f *testing.F) {
+	for _, seed := range escapeSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, fileId string) {
+		base, outside := escapeTree(t)
+		server := &NetworkVideoContentServer{Dir: base}
+		ctx := context.Background()
+
+		_, writeErr := server.Write(ctx, &pb.WriteRequest{FileId: fileId, Data: []byte("fuzz")})
+		checkOutside(t, outside)
+
+		read, readErr := server.Read(ctx, &pb.ReadRequest{FileId: fileId})
+		if readErr == nil && string(read.GetData()) == "secret" {
+			t.Fatalf("read %q returned the secret", fileId)
+		}
+
+		_, statErr := server.Stat(ctx, &pb.StatRequest{FileId: fileId})
+		_, deleteErr := server.Delete(ctx, &pb.DeleteRequest{FileId: fileId})
+		checkOutside(t, outside)
+
+		if unsafeId(fileId) {
+			for name, err := range map[string]error{"Write": writeErr, "Read": readErr, "Stat": statErr, "Delete": deleteErr} {
+				if err == nil {
+					t.Errorf("%s accepted unsafe file id %q", name, fileId)
+				}
+			}
+		}
+	})
+}
+
+func TestSymlinksAreNotFollowed(t *testing.T) {
+	base, outside := escapeTree(t)
+	server := &NetworkVideoContentServer{Dir: base}
+	ctx := context.Background()
+
+	for _, fileId := range []string{"link/secret", "video/link"} {
+		if _, err := server.Read(ctx, &pb.ReadRequest{FileId: fileId}); err == nil {
+			t.Errorf("Read %q followed a symlink out of the base directory", fileId)
+		}
+		if _, err := server.Stat(ctx, &pb.StatRequest{FileId: fileId}); err == nil {
+			t.Errorf("Stat %q followed a symlink out of the base directory", fileId)
+		}
+	}
+	if _, err := server.Write(ctx, &pb.WriteRequest{FileId: "link/secret", Data: []byte("fuzz")}); err == nil {
+		t.Errorf("Write followed a symlink out of the base directory")
+	}
+	checkOutside(t, outside)
+}
+
+// listAll pages through List, returning every file id listed and the number
+// of pages it took.
+func listAll(t *testing.T, server *NetworkVideoContentServer, req *pb.ListRequest) ([]string, int) {
+	t.Helper()
+	var fileIds []string
+	var pages int
+	for {
+		response, err := server.List(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		if len(response.GetFiles()) > int(req.GetPageSize()) {
+			t.Fatalf("a page of %d files has %d", req.GetPageSize(), len(response.GetFiles()))
+		}
+		for _, file := range response.GetFiles() {
+			fileIds = append(fileIds, file.GetFileId())
+		}
+		if response.GetNextPageToken() == "" {
+			return fileIds, pages
+		}
+		req.PageToken = response.GetNextPageToken()
+	}
+}
+
+func TestListPages(t *testing.T) {
+	server := &NetworkVideoContentServer{Dir: t.TempDir()}
+	var all []string
+	for _, videoId := range []string{"alpha", "beta", "gamma"} {
+		for _, filename := range []string{"manifest.mpd", "init.m4s", "segment1.m4s"} {
+			fileId := videoId + "/" + filename
+			err := server.writeFile(fileId, []byte(fileId), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, fileId)
+		}
+	}
+	slices.Sort(all)
+
+	tests := []struct {
+		prefix   string
+		pageSize int32
+		want     []string
+		pages    int
+	}{
+		{"", 3, all, 3},
+		{"", 4, all, 3},
+		{"", 100, all, 1},
+		{"beta", 2, all[3:6], 2},
+		{"delta", 2, nil, 1},
+	}
+	for _, test := range tests {
+		fileIds, pages := listAll(t, server, &pb.ListRequest{VideoIdPrefix: test.prefix, PageSize: test.pageSize})
+		slices.Sort(fileIds)
+		if !slices.Equal(fileIds, test.want) || pages != test.pages {
+			t.Errorf("listing %q in pages of %d = %v in %d pages, want %v in %d", test.prefix, test.pageSize, fileIds, pages, test.want, test.pages)
+		}
+	}
+}
+
+func TestListHashRange(t *testing.T) {
+	server := &NetworkVideoContentServer{Dir: t.TempDir()}
+	var hashes []uint64
+	byHash := make(map[uint64]string)
+	for i := range 8 {
+		fileId := fmt.Sprintf("video%d/manifest.mpd", i)
+		err := server.writeFile(fileId, []byte(fileId), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hashring.Hash(fileId))
+		byHash[hashring.Hash(fileId)] = fileId
+	}
+	slices.Sort(hashes)
+
+	tests := []struct {
+		name       string
+		start, end uint64
+		want       []uint64
+	}{
+		// Ranges include their start but not their end
+		{"middle", hashes[2], hashes[5], hashes[2:5]},
+		{"wrapping", hashes[6], hashes[1], []uint64{hashes[6], hashes[7], hashes[0]}},
+		{"single file", hashes[3], hashes[3] + 1, hashes[3:4]},
+	}
+	for _, test := range tests {
+		var want []string
+		for _, hash := range test.want {
+			want = append(want, byHash[hash])
+		}
+		slices.Sort(want)
+
+		fileIds, _ := listAll(t, server, &pb.ListRequest{PageSize: 2, HashRange: &pb.HashRange{Start: test.start, End: test.end}})
+		slices.Sort(fileIds)
+		if !slices.Equal(fileIds, want) {
+			t.Errorf("listing the %s range = %v, want %v", test.name, fileIds, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+	"tritontube/internal/logging"
+	"tritontube/internal/metrics"
+	pb "tritontube/internal/proto"
+	"tritontube/internal/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// rateLimiter spreads byte transfers out so that, across all callers, no more
+// than rate bytes are sent per second. A rate of zero disables limiting.
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate int64
+	next time.Time
+}
+
+// wait blocks until n bytes may be sent.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	// Reserve a slot of n/rate seconds after the previous reservation
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	start := l.next
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.rate) * float64(time.Second)))
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Transfer moves files from this node straight to another storage node, so
+// that rebalancing does not have to go through the web server.
+func (s *NetworkVideoContentServer) Transfer(ctx context.Context, req *pb.TransferRequest) (*pb.TransferResponse, error) {
+	// Over TLS the destination has to prove it belongs to the cluster when
+	// dialed, but in plaintext only the configured peers can be trusted
+	if !s.TLSEnabled && !slices.Contains(s.TransferPeers, "*") && !slices.Contains(s.TransferPeers, req.GetDestination()) {
+		slog.WarnContext(ctx, "Refusing to transfer to unknown node", "node", req.GetDestination())
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not a transfer peer", req.GetDestination())
+	}
+	creds := s.ClientCredentials
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(req.GetDestination(),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor, logging.UnaryClientInterceptor),
+		tracing.DialOption(),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while connecting", "node", req.GetDestination(), "err", err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer conn.Close()
+	client := pb.NewNetworkVideoContentClient(conn)
+
+	parallelism := int(req.GetParallelism())
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	limiter := &rateLimiter{rate: req.GetBytesPerSecond()}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		count    int32
+		bytes    int64
+		wg       sync.WaitGroup
+	)
+	fileIds := make(chan string)
+
+	for range parallelism {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fileId := range fileIds {
+				n, err := s.transferFile(ctx, client, limiter, fileId, req.GetKeepSource())
+				err = statusError(err)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						slog.ErrorContext(ctx, "Error while transferring", append(fileFields(fileId), "node", req.GetDestination(), "err", err)...)
+						firstErr = err
+						cancel()
+					}
+				} else {
+					count++
+					bytes += int64(n)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, fileId := range req.GetFileIds() {
+		select {
+		case fileIds <- fileId:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(fileIds)
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = statusError(ctx.Err())
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return &pb.TransferResponse{TransferredFileCount: count, TransferredBytes: bytes}, nil
+}
+
+// transferFile copies a single file to the destination, checks that the
+// destination holds an identical copy, and then removes the local copy unless
+// keepSource is set.
+func (s *NetworkVideoContentServer) transferFile(ctx context.Context, client pb.NetworkVideoContentClient, limiter *rateLimiter, fileId string, keepSource bool) (int, error) {
+	data, checksum, err := s.readFile(fileId)
+	if err != nil {
+		return 0, err
+	}
+
+	err = limiter.wait(ctx, len(data))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = client.Write(ctx, &pb.WriteRequest{FileId: fileId, Data: data, Sha256: checksum})
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := client.Stat(ctx, &pb.StatRequest{FileId: fileId})
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(response.GetSha256(), checksum) {
+		return 0, status.Errorf(codes.DataLoss, "checksum mismatch for %s after transfer", fileId)
+	}
+
+	if keepSource {
+		return len(data), nil
+	}
+
+	err = s.removeFile(fileId)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
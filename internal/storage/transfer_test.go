@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "tritontube/internal/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestTransferOnlyToPeers(t *testing.T) {
+	ctx := context.Background()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination := &NetworkVideoContentServer{Dir: t.TempDir()}
+	gs := grpc.NewServer()
+	pb.RegisterNetworkVideoContentServer(gs, destination)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	source := &NetworkVideoContentServer{Dir: t.TempDir()}
+	err = source.writeFile("video/manifest.mpd", []byte("manifest"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &pb.TransferRequest{FileIds: []string{"video/manifest.mpd"}, Destination: lis.Addr().String()}
+
+	// The binary passes plaintext credentials rather than none without TLS
+	for name, creds := range map[string]credentials.TransportCredentials{"none": nil, "insecure": insecure.NewCredentials()} {
+		source.ClientCredentials = creds
+		_, err = source.Transfer(ctx, req)
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Transfer with %s credentials to a node that is not a peer = %v, want PermissionDenied", name, err)
+		}
+	}
+	if _, _, err := destination.readFile("video/manifest.mpd"); err == nil {
+		t.Fatal("the file reached a node that is not a peer")
+	}
+
+	source.TransferPeers = []string{lis.Addr().String()}
+	response, err := source.Transfer(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.GetTransferredFileCount() != 1 {
+		t.Errorf("transferred %d files, want 1", response.GetTransferredFileCount())
+	}
+	data, _, err := destination.readFile("video/manifest.mpd")
+	if err != nil || string(data) != "manifest" {
+		t.Errorf("reading the transferred file = %q, %v, want \"manifest\"", data, err)
+	}
+}
+
+func TestRateLimiterSpacesSends(t *testing.T) {
+	limiter := &rateLimiter{rate: 1000}
+	start := time.Now()
+	for range 3 {
+		err := limiter.wait(context.Background(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first send goes straight away and each one after it waits 100ms
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("sending 300 bytes at 1000 bytes per second took %v, want at least 200ms", elapsed)
+	}
+
+	// Waiting for a later slot gives up when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter = &rateLimiter{rate: 1000, next: time.Now().Add(time.Minute)}
+	if err := limiter.wait(ctx, 100); err != context.Canceled {
+		t.Errorf("waiting with a cancelled context = %v, want context.Canceled", err)
+	}
+
+	// A rate of zero never waits
+	if err := (&rateLimiter{}).wait(ctx, 1<<30); err != nil {
+		t.Errorf("waiting without a limit = %v", err)
+	}
+}
+
+// slowServer is a storage node that takes a while to write, recording the most
+// writes it had in progress at once.
+type slowServer struct {
+	*NetworkVideoContentServer
+	mu      sync.Mutex
+	writing int
+	most    int
+}
+
+func (s *slowServer) Write(ctx context.Context, req *pb.WriteRequest) (*pb.WriteResponse, error) {
+	s.mu.Lock()
+	s.writing++
+	s.most = max(s.most, s.writing)
+	s.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	defer func() {
+		s.mu.Lock()
+		s.writing--
+		s.mu.Unlock()
+	}()
+	return s.NetworkVideoContentServer.Write(ctx, req)
+}
+
+func TestTransferParallelism(t *testing.T) {
+	ctx := context.Background()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination := &slowServer{NetworkVideoContentServer: &NetworkVideoContentServer{Dir: t.TempDir()}}
+	gs := grpc.NewServer()
+	pb.RegisterNetworkVideoContentServer(gs, destination)
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	source := &NetworkVideoContentServer{Dir: t.TempDir(), TransferPeers: []string{"*"}}
+	var fileIds []string
+	for i := range 6 {
+		fileId := fmt.Sprintf("video/segment%d.m4s", i)
+		err = source.writeFile(fileId, []byte("segment"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileIds = append(fileIds, fileId)
+	}
+
+	response, err := source.Transfer(ctx, &pb.TransferRequest{FileIds: fileIds, Destination: lis.Addr().String(), Parallelism: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.GetTransferredFileCount() != 6 {
+		t.Errorf("transferred %d files, want 6", response.GetTransferredFileCount())
+	}
+	if destination.most != 2 {
+		t.Errorf("%d files were written at once, want 2", destination.most)
+	}
+}
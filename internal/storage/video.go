@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"tritontube/internal/fileid"
+	pb "tritontube/internal/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// listVideo lists the files of a single video, or returns an error wrapping
+// fs.ErrNotExist if the node holds none.
+func (s *NetworkVideoContentServer) listVideo(videoId string) ([]*pb.FileInfo, error) {
+	err := fileid.ValidateName(videoId)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*pb.FileInfo
+	err = s.walkFiles(listFilter{videoIdPrefix: videoId}, func(file *pb.FileInfo) error {
+		// The prefix also matches longer video ids
+		if strings.HasPrefix(file.GetFileId(), videoId+"/") {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("video %s: %w", videoId, fs.ErrNotExist)
+	}
+
+	return files, nil
+}
+
+func (s *NetworkVideoContentServer) DeleteVideo(ctx context.Context, req *pb.DeleteVideoRequest) (*pb.DeleteVideoResponse, error) {
+	count, err := s.removeVideo(req.GetVideoId())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, status.Errorf(codes.NotFound, "video %s not found", req.GetVideoId())
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error while deleting video", "video_id", req.GetVideoId(), "err", err)
+		return nil, statusError(err)
+	}
+
+	return &pb.DeleteVideoResponse{DeletedFileCount: int32(count)}, nil
+}
+
+// removeVideo deletes every file of a video, returning how many files it
+// held.
+func (s *NetworkVideoContentServer) removeVideo(videoId string) (int, error) {
+	unlock := s.lockVideo(videoId)
+	defer unlock()
+
+	files, err := s.listVideo(videoId)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, file := range files {
+		size += file.GetSize()
+	}
+
+	if s.QuotaBytes > 0 {
+		// As in releaseSpace, count usage either before or after the delete
+		s.usageMu.Lock()
+		defer s.usageMu.Unlock()
+		err = s.loadUsage()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = s.backend().DeleteVideo(videoId)
+	if err != nil {
+		return 0, err
+	}
+	if s.QuotaBytes > 0 {
+		s.usedBytes -= size
+	}
+
+	return len(files), nil
+}
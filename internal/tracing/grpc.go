@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+
+	pb "tritontube/internal/proto"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// traced leaves out the health probes sent to every storage node every few
+// seconds, which would otherwise bury the traces of real requests.
+func traced(info *stats.RPCTagInfo) bool {
+	return !strings.HasPrefix(info.FullMethodName, "/grpc.health.v1.Health/") &&
+		info.FullMethodName != pb.NetworkVideoContent_Capacity_FullMethodName
+}
+
+// ServerOption traces the calls a gRPC server handles, continuing the trace
+// of the caller.
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(traced)))
+}
+
+// DialOption traces the calls a gRPC client makes, and passes the trace on to
+// the server.
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithFilter(traced)))
+}
+
+// Handler traces the requests served by an HTTP handler under route,
+// continuing the trace of the client if it sent one.
+func Handler(route string, handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(handler, route)
+}
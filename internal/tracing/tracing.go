@@ -0,0 +1,94 @@
+// Package tracing sets up OpenTelemetry tracing, so that a request can be
+// followed from the web server through to the storage node that served it.
+// Trace context travels between binaries in W3C traceparent headers.
+package tracing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options choose where spans are sent.
+type Options struct {
+	// Exporter is none, stdout or otlp.
+	Exporter string
+	// Endpoint is the URL of the OTLP gRPC collector, e.g.
+	// http://localhost:4317. If empty, the OTEL_EXPORTER_OTLP_* environment
+	// variables apply.
+	Endpoint string
+}
+
+// Flags registers the -trace-exporter and -trace-endpoint flags and returns
+// the options they set once flags are parsed.
+func Flags() *Options {
+	options := &Options{}
+	flag.StringVar(&options.Exporter, "trace-exporter", "none", "Where to send trace spans: none, stdout or otlp")
+	flag.StringVar(&options.Endpoint, "trace-endpoint", "", "URL of the OTLP gRPC collector for the otlp exporter, e.g. http://localhost:4317")
+	return options
+}
+
+// Setup installs a tracer provider that exports spans as the options say,
+// naming this binary serviceName. Trace context is passed on between binaries
+// even if spans are not exported. The returned function flushes any spans not
+// yet exported, and should be called before exiting.
+func (o Options) Setup(serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch o.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		var options []otlptracegrpc.Option
+		if o.Endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpointURL(o.Endpoint))
+		}
+		exporter, err = otlptracegrpc.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", o.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the one in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer("tritontube").Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends a span, marking it as failed if err is set.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,174 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	pb "tritontube/internal/proto"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// recordSpans installs a tracer provider that keeps every span ended, until
+// the test is over.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestSpansNest(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("disk on fire"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	if childSpan.Parent().SpanID() != parentSpan.SpanContext().SpanID() || childSpan.SpanContext().TraceID() != parentSpan.SpanContext().TraceID() {
+		t.Errorf("the child span is not part of its parent's trace")
+	}
+	if childSpan.Status().Code != codes.Error || childSpan.Status().Description != "disk on fire" || len(childSpan.Events()) != 1 {
+		t.Errorf("a failed span has status %v and %d events, want the error recorded", childSpan.Status(), len(childSpan.Events()))
+	}
+	if parentSpan.Status().Code != codes.Unset {
+		t.Errorf("a successful span has status %v", parentSpan.Status())
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	// Trace context is passed on even when spans are not exported
+	shutdown, err := Options{Exporter: "none"}.Setup("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shutdown(context.Background())
+	if fields := otel.GetTextMapPropagator().Fields(); !slices.Contains(fields, "traceparent") {
+		t.Errorf("propagated fields = %v, want traceparent", fields)
+	}
+
+	_, err = Options{Exporter: "zipkin"}.Setup("test")
+	if err == nil {
+		t.Error("Setup with an unknown exporter succeeded")
+	}
+}
+
+// statServer answers Stat, standing in for a storage node.
+type statServer struct {
+	pb.UnimplementedNetworkVideoContentServer
+}
+
+func (statServer) Stat(ctx context.Context, req *pb.StatRequest) (*pb.StatResponse, error) {
+	return &pb.StatResponse{FileId: req.GetFileId()}, nil
+}
+
+func TestGRPCContinuesTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer(ServerOption())
+	pb.RegisterNetworkVideoContentServer(gs, statServer{})
+	healthpb.RegisterHealthServer(gs, health.NewServer())
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()), DialOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, err = pb.NewNetworkVideoContentClient(conn).Stat(ctx, &pb.StatRequest{FileId: "video/manifest.mpd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Health probes are left out
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+	gs.GracefulStop()
+
+	traceId := parent.SpanContext().TraceID()
+	names := make(map[string]bool)
+	for _, span := range recorder.Ended() {
+		names[span.Name()] = true
+		if span.SpanContext().TraceID() != traceId {
+			t.Errorf("span %s is in trace %s, want %s", span.Name(), span.SpanContext().TraceID(), traceId)
+		}
+		if span.Name() == pb.NetworkVideoContent_Stat_FullMethodName[1:] && span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			names["client span under parent"] = true
+		}
+	}
+	if len(recorder.Ended()) != 3 || !names["client span under parent"] {
+		t.Errorf("recorded spans %v, want the parent and a client and server span for Stat", names)
+	}
+}
+
+func TestHandlerContinuesTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var handlerSpan bool
+	handler := Handler("/content/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "handler")
+		span.End()
+		handlerSpan = true
+	}))
+
+	// The client's trace is continued
+	ctx, client := Start(context.Background(), "client")
+	req := httptest.NewRequest(http.MethodGet, "/content/video/manifest.mpd", nil)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	client.End()
+
+	if !handlerSpan {
+		t.Fatal("the handler was not called")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	inner, server := spans[0], spans[1]
+	if server.Name() != "/content/" || server.Parent().SpanID() != client.SpanContext().SpanID() {
+		t.Errorf("the server span %s has parent %s, want /content/ under the client span", server.Name(), server.Parent().SpanID())
+	}
+	if inner.Parent().SpanID() != server.SpanContext().SpanID() || inner.SpanContext().TraceID() != client.SpanContext().TraceID() {
+		t.Error("spans started by the handler are not under the server span")
+	}
+}
@@ -0,0 +1,277 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	pb "tritontube/internal/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Role is what a caller of the admin service is allowed to do. Each role may
+// do everything the roles before it may.
+type Role int
+
+const (
+	RoleNone Role = iota
+	// RoleViewer may look at the cluster but not change it.
+	RoleViewer
+	// RoleOperator may also add, remove and drain nodes.
+	RoleOperator
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole parses a role by the name String gives it.
+func ParseRole(name string) (Role, error) {
+	switch name {
+	case "none":
+		return RoleNone, nil
+	case "viewer":
+		return RoleViewer, nil
+	case "operator":
+		return RoleOperator, nil
+	default:
+		return RoleNone, fmt.Errorf("unknown role %q", name)
+	}
+}
+
+// adminMethodRoles is the role each admin method needs. Methods missing from
+// it need RoleOperator.
+var adminMethodRoles = map[string]Role{
+	pb.VideoContentAdminService_ListNodes_FullMethodName:          RoleViewer,
+	pb.VideoContentAdminService_GetClusterStatus_FullMethodName:   RoleViewer,
+	pb.VideoContentAdminService_PlanTopologyChange_FullMethodName: RoleViewer,
+	pb.VideoContentAdminService_WatchRing_FullMethodName:          RoleViewer,
+	pb.VideoContentAdminService_AddNode_FullMethodName:            RoleOperator,
+	pb.VideoContentAdminService_RemoveNode_FullMethodName:         RoleOperator,
+	pb.VideoContentAdminService_DrainNode_FullMethodName:          RoleOperator,
+}
+
+// AdminToken is a bearer token that grants a role. Name identifies the holder
+// in the audit log, so the token itself is never logged.
+type AdminToken struct {
+	Name  string
+	Token string
+	Role  Role
+}
+
+// AdminAccess decides who may call the admin service. Callers identify
+// themselves with a bearer token in the authorization metadata, or with the
+// common name of their TLS client certificate. Anyone else gets
+// AnonymousRole, so that by default nobody may call the service until tokens
+// or identities are configured.
+type AdminAccess struct {
+	Tokens []AdminToken
+	// Identities maps client certificate common names to roles.
+	Identities map[string]Role
+	// AnonymousRole is the role of callers without a valid token or a listed
+	// certificate.
+	AnonymousRole Role
+}
+
+// LoadAdminTokens reads bearer tokens from a file with one "name role token"
+// entry per line. Blank lines and lines starting with # are skipped.
+func LoadAdminTokens(path string) ([]AdminToken, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tokens []AdminToken
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected name, role and token", path, lineNumber)
+		}
+		role, err := ParseRole(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		tokens = append(tokens, AdminToken{Name: fields[0], Role: role, Token: fields[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// LoadAdminAccess builds an AdminAccess from an optional tokens file,
+// comma-separated lists of the client certificate names of operators and
+// viewers, and the name of the role everyone else gets.
+func LoadAdminAccess(tokensPath string, operators string, viewers string, anonymous string) (AdminAccess, error) {
+	anonymousRole, err := ParseRole(anonymous)
+	if err != nil {
+		return AdminAccess{}, err
+	}
+
+	access := AdminAccess{Identities: make(map[string]Role), AnonymousRole: anonymousRole}
+	if tokensPath != "" {
+		tokens, err := LoadAdminTokens(tokensPath)
+		if err != nil {
+			return AdminAccess{}, err
+		}
+		access.Tokens = tokens
+	}
+
+	for _, name := range strings.Split(viewers, ",") {
+		if name != "" {
+			access.Identities[name] = RoleViewer
+		}
+	}
+	for _, name := range strings.Split(operators, ",") {
+		if name != "" {
+			access.Identities[name] = RoleOperator
+		}
+	}
+
+	return access, nil
+}
+
+// authenticate works out who a caller is and what role they have. A valid
+// token takes precedence over the client certificate.
+func (a AdminAccess) authenticate(ctx context.Context) (string, Role) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &tlsInfo.State
+		}
+	}
+	return a.identify(md.Get("authorization"), state)
+}
+
+// authenticateHTTP does the same for an HTTP request.
+func (a AdminAccess) authenticateHTTP(r *http.Request) (string, Role) {
+	return a.identify(r.Header.Values("Authorization"), r.TLS)
+}
+
+// identify finds the caller presenting the given authorization values and
+// TLS connection, which is nil for plaintext.
+func (a AdminAccess) identify(authorization []string, state *tls.ConnectionState) (string, Role) {
+	for _, value := range authorization {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if !ok {
+			continue
+		}
+		// Compare every token in constant time, so that timing does not
+		// give away how much of a guess was right
+		var match *AdminToken
+		for i := range a.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(a.Tokens[i].Token)) == 1 {
+				match = &a.Tokens[i]
+			}
+		}
+		if match != nil {
+			return "token:" + match.Name, match.Role
+		}
+		return "invalid-token", RoleNone
+	}
+
+	if state != nil && len(state.VerifiedChains) > 0 {
+		commonName := state.VerifiedChains[0][0].Subject.CommonName
+		return "cert:" + commonName, max(a.Identities[commonName], a.AnonymousRole)
+	}
+
+	return "anonymous", a.AnonymousRole
+}
+
+// authorize checks that the caller may call a method, returning who they are
+// for the audit log.
+func (a AdminAccess) authorize(ctx context.Context, method string) (string, error) {
+	caller, role := a.authenticate(ctx)
+
+	required, ok := adminMethodRoles[method]
+	if !ok {
+		required = RoleOperator
+	}
+
+	if role == RoleNone {
+		return caller, status.Error(codes.Unauthenticated, "a valid token or client certificate is required")
+	}
+	if role < required {
+		return caller, status.Errorf(codes.PermissionDenied, "%s needs the %v role, but %s is a %v", method, required, caller, role)
+	}
+	return caller, nil
+}
+
+// audit records an admin call along with who made it and how it went.
+func (s *NetworkVideoContentService) audit(ctx context.Context, caller string, method string, req any, started time.Time, err error) {
+	logger := s.AdminAuditLog
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	address := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+
+	attrs := []any{"caller", caller, "address", address, "method", method}
+	if req != nil {
+		attrs = append(attrs, "request", fmt.Sprint(req))
+	}
+	attrs = append(attrs, "result", status.Code(err).String(), "duration", time.Since(started))
+	logger.InfoContext(ctx, "Admin call", attrs...)
+}
+
+// adminUnaryInterceptor authorizes and audits every unary admin call.
+func (s *NetworkVideoContentService) adminUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	started := time.Now()
+
+	caller, err := s.AdminAccess.authorize(ctx, info.FullMethod)
+	if err != nil {
+		s.audit(ctx, caller, info.FullMethod, req, started, err)
+		return nil, err
+	}
+
+	response, err := handler(ctx, req)
+	s.audit(ctx, caller, info.FullMethod, req, started, err)
+	return response, err
+}
+
+// adminStreamInterceptor does the same for streaming admin calls.
+func (s *NetworkVideoContentService) adminStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	started := time.Now()
+
+	caller, err := s.AdminAccess.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		s.audit(stream.Context(), caller, info.FullMethod, nil, started, err)
+		return err
+	}
+
+	err = handler(srv, stream)
+	s.audit(stream.Context(), caller, info.FullMethod, nil, started, err)
+	return err
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	pb "tritontube/internal/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminAccessAuthorize(t *testing.T) {
+	tokens := []AdminToken{{Name: "alice", Token: "operator-token", Role: RoleOperator}, {Name: "bob", Token: "viewer-token", Role: RoleViewer}}
+	list := pb.VideoContentAdminService_ListNodes_FullMethodName
+	add := pb.VideoContentAdminService_AddNode_FullMethodName
+
+	tests := []struct {
+		name   string
+		access AdminAccess
+		token  string
+		method string
+		want   codes.Code
+	}{
+		{"nothing configured", AdminAccess{}, "", list, codes.Unauthenticated},
+		{"anonymous viewer may view", AdminAccess{AnonymousRole: RoleViewer}, "", list, codes.OK},
+		{"anonymous viewer may not change", AdminAccess{AnonymousRole: RoleViewer}, "", add, codes.PermissionDenied},
+		{"anonymous operator", AdminAccess{AnonymousRole: RoleOperator}, "", add, codes.OK},
+		{"operator token", AdminAccess{Tokens: tokens}, "operator-token", add, codes.OK},
+		{"viewer token", AdminAccess{Tokens: tokens}, "viewer-token", add, codes.PermissionDenied},
+		{"no token", AdminAccess{Tokens: tokens}, "", list, codes.Unauthenticated},
+		{"wrong token", AdminAccess{Tokens: tokens, AnonymousRole: RoleOperator}, "guess", list, codes.Unauthenticated},
+	}
+	for _, test := range tests {
+		ctx := context.Background()
+		if test.token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+test.token))
+		}
+		_, err := test.access.authorize(ctx, test.method)
+		if status.Code(err) != test.want {
+			t.Errorf("%s: authorize = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestLoadAdminAccessAnonymousRole(t *testing.T) {
+	access, err := LoadAdminAccess("", "", "", "none")
+	if err != nil || access.AnonymousRole != RoleNone {
+		t.Errorf("LoadAdminAccess with none = %v, %v", access.AnonymousRole, err)
+	}
+	if _, err := LoadAdminAccess("", "", "", "admin"); err == nil {
+		t.Errorf("LoadAdminAccess accepted an unknown anonymous role")
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	s := &NetworkVideoContentService{AdminAuditLog: slog.New(slog.NewTextHandler(&buf, nil))}
+
+	s.audit(context.Background(), "token:alice", "/admin/AddNode", &pb.AddNodeRequest{NodeAddress: "127.0.0.1:9000"}, time.Now(), status.Error(codes.PermissionDenied, "no"))
+
+	line := buf.String()
+	for _, want := range []string{"caller=token:alice", "method=/admin/AddNode", "127.0.0.1:9000", "result=PermissionDenied"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("audit log %q is missing %q", line, want)
+		}
+	}
+}
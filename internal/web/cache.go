@@ -0,0 +1,348 @@
+package web
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CachedVideoContentService keeps recently read files of another content
+// service in memory, evicting the least recently used once they take up more
+// than MaxBytes. Concurrent reads of a file that is not cached share a single
+// read from the underlying service. Files written or deleted through the
+// cache are dropped from it, but changes made through other web servers are
+// not seen until the files are evicted.
+//
+// The data returned by Read is shared between callers and must not be
+// modified.
+type CachedVideoContentService struct {
+	VideoContentService
+	// MaxBytes bounds the total size of the cached files. Files larger than
+	// this are never cached.
+	MaxBytes int64
+
+	mu sync.Mutex
+	// entries holds the cached files by video id and then filename, so that
+	// a video's files can be dropped without looking at any others
+	entries map[string]map[string]*list.Element
+	// lru holds the *cacheEntry values, most recently used first
+	lru   list.List
+	bytes int64
+	// reads are the reads from the underlying service in progress, indexed
+	// like entries. A read dropped from here by a write or delete does not
+	// cache what it finds.
+	reads map[string]map[string]*cacheRead
+}
+
+type cacheKey struct {
+	videoId  string
+	filename string
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	data []byte
+}
+
+// cacheRead is a read from the underlying service, shared by every caller
+// asking for the file while it runs.
+type cacheRead struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+func (s *CachedVideoContentService) Read(ctx context.Context, videoId string, filename string) ([]byte, error) {
+	key := cacheKey{videoId, filename}
+
+	s.mu.Lock()
+	if elem, ok := s.entries[videoId][filename]; ok {
+		s.lru.MoveToFront(elem)
+		data := elem.Value.(*cacheEntry).data
+		s.mu.Unlock()
+		contentCacheRequests.WithLabelValues("hit").Inc()
+		return data, nil
+	}
+	read, ok := s.reads[videoId][filename]
+	if ok {
+		contentCacheRequests.WithLabelValues("shared").Inc()
+	} else {
+		contentCacheRequests.WithLabelValues("miss").Inc()
+		read = &cacheRead{done: make(chan struct{})}
+		if s.reads == nil {
+			s.reads = make(map[string]map[string]*cacheRead)
+		}
+		if s.reads[videoId] == nil {
+			s.reads[videoId] = make(map[string]*cacheRead)
+		}
+		s.reads[videoId][filename] = read
+		// The read carries on for the other callers if this one gives up
+		go s.read(context.WithoutCancel(ctx), key, read)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-read.done:
+		return read.data, read.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// read reads a file from the underlying service for every caller waiting on
+// it, caching it unless the file was changed in the meantime.
+func (s *CachedVideoContentService) read(ctx context.Context, key cacheKey, read *cacheRead) {
+	read.data, read.err = s.VideoContentService.Read(ctx, key.videoId, key.filename)
+
+	s.mu.Lock()
+	if s.reads[key.videoId][key.filename] == read {
+		deleteNested(s.reads, key.videoId, key.filename)
+		if read.err == nil {
+			s.add(key, read.data)
+		}
+	}
+	s.mu.Unlock()
+
+	close(read.done)
+}
+
+// deleteNested removes an entry from a map indexed by video id and then
+// filename, along with the video's map once it is empty.
+func deleteNested[V any](m map[string]map[string]V, videoId string, filename string) {
+	delete(m[videoId], filename)
+	if len(m[videoId]) == 0 {
+		delete(m, videoId)
+	}
+}
+
+// add caches a file, evicting others to make room. s.mu must be held.
+func (s *CachedVideoContentService) add(key cacheKey, data []byte) {
+	size := int64(len(data))
+	if size > s.MaxBytes {
+		return
+	}
+	if _, ok := s.entries[key.videoId][key.filename]; ok {
+		return
+	}
+
+	for s.bytes+size > s.MaxBytes {
+		s.remove(s.lru.Back())
+	}
+	if s.entries == nil {
+		s.entries = make(map[string]map[string]*list.Element)
+	}
+	if s.entries[key.videoId] == nil {
+		s.entries[key.videoId] = make(map[string]*list.Element)
+	}
+	s.entries[key.videoId][key.filename] = s.lru.PushFront(&cacheEntry{key, data})
+	s.bytes += size
+	contentCacheBytes.Add(float64(size))
+}
+
+// remove drops a cached file. s.mu must be held.
+func (s *CachedVideoContentService) remove(elem *list.Element) {
+	entry := s.lru.Remove(elem).(*cacheEntry)
+	deleteNested(s.entries, entry.key.videoId, entry.key.filename)
+	s.bytes -= int64(len(entry.data))
+	contentCacheBytes.Sub(float64(len(entry.data)))
+}
+
+// invalidate drops the cached files of a video, or just one of them if
+// filename is not empty, and stops reads of them in progress from caching
+// anything.
+func (s *CachedVideoContentService) invalidate(videoId string, filename string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if filename != "" {
+		if elem, ok := s.entries[videoId][filename]; ok {
+			s.remove(elem)
+		}
+		// Later reads must not share ones that may return the old content
+		deleteNested(s.reads, videoId, filename)
+		return
+	}
+
+	for _, elem := range s.entries[videoId] {
+		s.remove(elem)
+	}
+	delete(s.reads, videoId)
+}
+
+func (s *CachedVideoContentService) Write(ctx context.Context, videoId string, filename string, data []byte) error {
+	err := s.VideoContentService.Write(ctx, videoId, filename, data)
+	s.invalidate(videoId, filename)
+	return err
+}
+
+func (s *CachedVideoContentService) Delete(ctx context.Context, videoId string) error {
+	err := s.VideoContentService.Delete(ctx, videoId)
+	s.invalidate(videoId, "")
+	return err
+}
+
+// Shutdown shuts down the cached service if it needs to be.
+func (s *CachedVideoContentService) Shutdown(ctx context.Context) error {
+	if service, ok := s.VideoContentService.(shutdowner); ok {
+		return service.Shutdown(ctx)
+	}
+	return nil
+}
+
+var _ VideoContentService = (*CachedVideoContentService)(nil)
+
+// metadataCacheEntries bounds the videos CachedVideoMetadataService remembers
+// reads of, so lookups of many missing ids cannot fill memory.
+const metadataCacheEntries = 10000
+
+// CachedVideoMetadataService remembers the results of reading and listing
+// another metadata service for a while. Videos created or deleted through the
+// cache are seen straight away, but changes made through other web servers
+// are only seen once the cached results expire.
+type CachedVideoMetadataService struct {
+	VideoMetadataService
+	// TTL is how long reads of existing videos and the list of videos are
+	// kept. Zero disables caching them.
+	TTL time.Duration
+	// NegativeTTL is how long reads of missing videos are kept. Zero disables
+	// caching them.
+	NegativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]metadataCacheEntry
+	list    []VideoMetadata
+	// listExpires is when list expires, zero if it is not cached
+	listExpires time.Time
+	// generation changes whenever videos are created or deleted, so reads
+	// that started before then do not cache what they find
+	generation uint64
+}
+
+// metadataCacheEntry is a cached read, with nil metadata for a missing video.
+type metadataCacheEntry struct {
+	metadata *VideoMetadata
+	expires  time.Time
+}
+
+func (s *CachedVideoMetadataService) Read(ctx context.Context, id string) (*VideoMetadata, error) {
+	s.mu.Lock()
+	entry, ok := s.entries[id]
+	generation := s.generation
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		metadataCacheRequests.WithLabelValues("Read", "hit").Inc()
+		return copyMetadata(entry.metadata), nil
+	}
+	metadataCacheRequests.WithLabelValues("Read", "miss").Inc()
+
+	metadata, err := s.VideoMetadataService.Read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.TTL
+	if metadata == nil {
+		ttl = s.NegativeTTL
+	}
+	if ttl > 0 {
+		s.mu.Lock()
+		if generation == s.generation {
+			s.addEntry(id, metadataCacheEntry{copyMetadata(metadata), time.Now().Add(ttl)})
+		}
+		s.mu.Unlock()
+	}
+	return metadata, nil
+}
+
+// addEntry caches a read, first making room by dropping expired reads, or any
+// read if none have expired. s.mu must be held.
+func (s *CachedVideoMetadataService) addEntry(id string, entry metadataCacheEntry) {
+	if s.entries == nil {
+		s.entries = make(map[string]metadataCacheEntry)
+	}
+	if _, ok := s.entries[id]; !ok && len(s.entries) >= metadataCacheEntries {
+		now := time.Now()
+		for id, entry := range s.entries {
+			if !now.Before(entry.expires) {
+				delete(s.entries, id)
+			}
+		}
+		for id := range s.entries {
+			if len(s.entries) < metadataCacheEntries {
+				break
+			}
+			delete(s.entries, id)
+		}
+	}
+	s.entries[id] = entry
+}
+
+func (s *CachedVideoMetadataService) List(ctx context.Context) ([]VideoMetadata, error) {
+	s.mu.Lock()
+	list, expires := s.list, s.listExpires
+	generation := s.generation
+	s.mu.Unlock()
+	if time.Now().Before(expires) {
+		metadataCacheRequests.WithLabelValues("List", "hit").Inc()
+		return slices.Clone(list), nil
+	}
+	metadataCacheRequests.WithLabelValues("List", "miss").Inc()
+
+	videos, err := s.VideoMetadataService.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.TTL > 0 {
+		s.mu.Lock()
+		if generation == s.generation {
+			s.list, s.listExpires = slices.Clone(videos), time.Now().Add(s.TTL)
+		}
+		s.mu.Unlock()
+	}
+	return videos, nil
+}
+
+// invalidate forgets what is cached about a video and the list of videos, and
+// stops reads in progress from caching anything.
+func (s *CachedVideoMetadataService) invalidate(videoId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	delete(s.entries, videoId)
+	s.list, s.listExpires = nil, time.Time{}
+}
+
+func (s *CachedVideoMetadataService) Create(ctx context.Context, videoId string, uploadedAt time.Time) error {
+	err := s.VideoMetadataService.Create(ctx, videoId, uploadedAt)
+	s.invalidate(videoId)
+	return err
+}
+
+func (s *CachedVideoMetadataService) Delete(ctx context.Context, videoId string) error {
+	err := s.VideoMetadataService.Delete(ctx, videoId)
+	s.invalidate(videoId)
+	return err
+}
+
+// Shutdown shuts down the cached service if it needs to be.
+func (s *CachedVideoMetadataService) Shutdown(ctx context.Context) error {
+	if service, ok := s.VideoMetadataService.(shutdowner); ok {
+		return service.Shutdown(ctx)
+	}
+	return nil
+}
+
+// copyMetadata copies cached metadata, so that callers cannot change it.
+func copyMetadata(metadata *VideoMetadata) *VideoMetadata {
+	if metadata == nil {
+		return nil
+	}
+	copied := *metadata
+	return &copied
+}
+
+var _ VideoMetadataService = (*CachedVideoMetadataService)(nil)
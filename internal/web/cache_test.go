@@ -0,0 +1,362 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryMetadataService keeps metadata in memory. If block is set, reads and
+// lists wait for it to be closed after looking the videos up, so that tests
+// can change the videos while a read is in progress.
+type memoryMetadataService struct {
+	mu     sync.Mutex
+	videos map[string]time.Time
+	block  chan struct{}
+}
+
+func (s *memoryMetadataService) Read(ctx context.Context, id string) (*VideoMetadata, error) {
+	s.mu.Lock()
+	uploadedAt, ok := s.videos[id]
+	block := s.block
+	s.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+
+	if !ok {
+		return nil, nil
+	}
+	return &VideoMetadata{Id: id, UploadedAt: uploadedAt}, nil
+}
+
+func (s *memoryMetadataService) List(ctx context.Context) ([]VideoMetadata, error) {
+	s.mu.Lock()
+	var videos []VideoMetadata
+	for id, uploadedAt := range s.videos {
+		videos = append(videos, VideoMetadata{Id: id, UploadedAt: uploadedAt})
+	}
+	block := s.block
+	s.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	return videos, nil
+}
+
+func (s *memoryMetadataService) Create(ctx context.Context, videoId string, uploadedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.videos == nil {
+		s.videos = make(map[string]time.Time)
+	}
+	s.videos[videoId] = uploadedAt
+	return nil
+}
+
+func (s *memoryMetadataService) Delete(ctx context.Context, videoId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.videos, videoId)
+	return nil
+}
+
+// newCachedMetadata caches an empty metadata service for far longer than any
+// test runs, so that only invalidation can make it see changes.
+func newCachedMetadata() (*CachedVideoMetadataService, *memoryMetadataService) {
+	backing := &memoryMetadataService{}
+	return &CachedVideoMetadataService{VideoMetadataService: backing, TTL: time.Hour, NegativeTTL: time.Hour}, backing
+}
+
+// checkVisible fails the test unless Read and List both agree on whether a
+// video exists.
+func checkVisible(t *testing.T, service VideoMetadataService, videoId string, want bool) {
+	t.Helper()
+	ctx := context.Background()
+
+	metadata, err := service.Read(ctx, videoId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (metadata != nil) != want {
+		t.Errorf("Read(%s) = %v, want the video to exist: %v", videoId, metadata, want)
+	}
+
+	videos, err := service.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := slices.ContainsFunc(videos, func(video VideoMetadata) bool { return video.Id == videoId })
+	if listed != want {
+		t.Errorf("List() = %v, want %s listed: %v", videos, videoId, want)
+	}
+}
+
+func TestMetadataCacheSeesCreate(t *testing.T) {
+	cache, _ := newCachedMetadata()
+
+	// Cache the video as missing, and the list without it
+	checkVisible(t, cache, "video", false)
+
+	if err := cache.Create(context.Background(), "video", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	checkVisible(t, cache, "video", true)
+}
+
+func TestMetadataCacheSeesDelete(t *testing.T) {
+	cache, _ := newCachedMetadata()
+	if err := cache.Create(context.Background(), "video", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cache the video and the list with it
+	checkVisible(t, cache, "video", true)
+
+	if err := cache.Delete(context.Background(), "video"); err != nil {
+		t.Fatal(err)
+	}
+	checkVisible(t, cache, "video", false)
+}
+
+// TestMetadataCacheIgnoresStaleReads checks that a read or list which looked
+// the videos up before a create does not cache what it found.
+func TestMetadataCacheIgnoresStaleReads(t *testing.T) {
+	cache, backing := newCachedMetadata()
+	backing.block = make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		cache.Read(context.Background(), "video")
+	}()
+	go func() {
+		defer wg.Done()
+		cache.List(context.Background())
+	}()
+
+	// Let both look the videos up before the create
+	time.Sleep(50 * time.Millisecond)
+	if err := cache.Create(context.Background(), "video", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	close(backing.block)
+	wg.Wait()
+
+	backing.block = nil
+	checkVisible(t, cache, "video", true)
+}
+
+// TestDeleteThroughCachedMetadata checks that a video deleted over HTTP
+// leaves the index straight away, even though the index was cached.
+func TestDeleteThroughCachedMetadata(t *testing.T) {
+	cache, _ := newCachedMetadata()
+	contentService := FSVideoContentService{FSDir: t.TempDir()}
+	s := NewServer(cache, contentService)
+	s.AdminAccess = AdminAccess{AnonymousRole: RoleOperator}
+	ctx := context.Background()
+
+	if err := cache.Create(ctx, "video", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := contentService.Write(ctx, "video", "manifest.mpd", []byte("manifest")); err != nil {
+		t.Fatal(err)
+	}
+
+	index := func() string {
+		recorder := httptest.NewRecorder()
+		s.handleIndex(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		return recorder.Body.String()
+	}
+	if !strings.Contains(index(), "/videos/video") {
+		t.Fatalf("index does not link to the video before it is deleted")
+	}
+
+	recorder := httptest.NewRecorder()
+	s.handleVideo(recorder, httptest.NewRequest(http.MethodDelete, "/videos/video", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("DELETE /videos/video = %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	if strings.Contains(index(), "/videos/video") {
+		t.Errorf("index still links to the video after it was deleted")
+	}
+	recorder = httptest.NewRecorder()
+	s.handleVideo(recorder, httptest.NewRequest(http.MethodGet, "/videos/video", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("GET /videos/video after delete = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+// memoryContentService keeps files in memory and counts reads of them. If
+// block is set, reads wait for it to be closed after looking the file up.
+type memoryContentService struct {
+	mu    sync.Mutex
+	files map[cacheKey][]byte
+	reads map[cacheKey]int
+	block chan struct{}
+}
+
+func (s *memoryContentService) Read(ctx context.Context, videoId string, filename string) ([]byte, error) {
+	key := cacheKey{videoId, filename}
+	s.mu.Lock()
+	data, ok := s.files[key]
+	if s.reads == nil {
+		s.reads = make(map[cacheKey]int)
+	}
+	s.reads[key]++
+	block := s.block
+	s.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (s *memoryContentService) Write(ctx context.Context, videoId string, filename string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = make(map[cacheKey][]byte)
+	}
+	s.files[cacheKey{videoId, filename}] = data
+	return nil
+}
+
+func (s *memoryContentService) Delete(ctx context.Context, videoId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.files {
+		if key.videoId == videoId {
+			delete(s.files, key)
+		}
+	}
+	return nil
+}
+
+func (s *memoryContentService) readCount(videoId string, filename string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads[cacheKey{videoId, filename}]
+}
+
+// checkContent fails the test unless reading a file through the cache
+// returns want, or fails if want is empty.
+func checkContent(t *testing.T, cache *CachedVideoContentService, videoId string, filename string, want string) {
+	t.Helper()
+	data, err := cache.Read(context.Background(), videoId, filename)
+	if want == "" {
+		if err == nil {
+			t.Errorf("Read(%s, %s) = %q, want an error", videoId, filename, data)
+		}
+		return
+	}
+	if err != nil || string(data) != want {
+		t.Errorf("Read(%s, %s) = %q, %v, want %q", videoId, filename, data, err, want)
+	}
+}
+
+func TestContentCacheInvalidation(t *testing.T) {
+	backing := &memoryContentService{}
+	cache := &CachedVideoContentService{VideoContentService: backing, MaxBytes: 1 << 20}
+	ctx := context.Background()
+
+	for _, key := range []cacheKey{{"a", "1"}, {"a", "2"}, {"b", "1"}} {
+		if err := cache.Write(ctx, key.videoId, key.filename, []byte(key.videoId+key.filename)); err != nil {
+			t.Fatal(err)
+		}
+		checkContent(t, cache, key.videoId, key.filename, key.videoId+key.filename)
+		checkContent(t, cache, key.videoId, key.filename, key.videoId+key.filename)
+		if count := backing.readCount(key.videoId, key.filename); count != 1 {
+			t.Errorf("%v read %d times from the underlying service, want once", key, count)
+		}
+	}
+
+	// Writing a file drops only that file
+	if err := cache.Write(ctx, "a", "1", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	checkContent(t, cache, "a", "1", "new")
+	checkContent(t, cache, "a", "2", "a2")
+	if backing.readCount("a", "1") != 2 || backing.readCount("a", "2") != 1 {
+		t.Errorf("writing a/1 dropped the wrong files from the cache")
+	}
+
+	// Deleting a video drops all of its files and none of the others
+	if err := cache.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	checkContent(t, cache, "a", "1", "")
+	checkContent(t, cache, "a", "2", "")
+	checkContent(t, cache, "b", "1", "b1")
+	if backing.readCount("b", "1") != 1 {
+		t.Errorf("deleting video a dropped video b from the cache")
+	}
+	if len(cache.entries["a"]) != 0 || len(cache.entries) != 1 {
+		t.Errorf("cache still indexes %v after video a was deleted", cache.entries)
+	}
+}
+
+// TestContentCacheIgnoresStaleReads checks that a read which started before
+// a write does not cache the old content.
+func TestContentCacheIgnoresStaleReads(t *testing.T) {
+	backing := &memoryContentService{}
+	cache := &CachedVideoContentService{VideoContentService: backing, MaxBytes: 1 << 20}
+	ctx := context.Background()
+	if err := backing.Write(ctx, "video", "file", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	backing.block = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Read(ctx, "video", "file")
+	}()
+	for backing.readCount("video", "file") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := cache.Write(ctx, "video", "file", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	close(backing.block)
+	<-done
+
+	backing.mu.Lock()
+	backing.block = nil
+	backing.mu.Unlock()
+	checkContent(t, cache, "video", "file", "new")
+}
+
+func TestContentCacheEviction(t *testing.T) {
+	backing := &memoryContentService{}
+	cache := &CachedVideoContentService{VideoContentService: backing, MaxBytes: 10}
+	ctx := context.Background()
+
+	for _, filename := range []string{"1", "2", "3"} {
+		if err := backing.Write(ctx, "video", filename, []byte("four")); err != nil {
+			t.Fatal(err)
+		}
+		checkContent(t, cache, "video", filename, "four")
+	}
+
+	// Only the last two fit, so the first was evicted
+	if cache.bytes != 8 || len(cache.entries["video"]) != 2 {
+		t.Errorf("cache holds %d bytes in %v, want the last two files", cache.bytes, cache.entries)
+	}
+	checkContent(t, cache, "video", "1", "four")
+	if count := backing.readCount("video", "1"); count != 2 {
+		t.Errorf("evicted file read %d times from the underlying service, want twice", count)
+	}
+}
@@ -0,0 +1,179 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	pb "tritontube/internal/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// drainBatchSize is the number of files sent per Transfer call while draining,
+// which sets how often drain progress is updated.
+const drainBatchSize = 32
+
+// isDraining reports whether a node has been taken out of the write ring by a
+// drain that has not yet completed. The caller must hold s.mu.
+func (s *NetworkVideoContentService) isDraining(nodeId string) bool {
+	drain, ok := s.drains[nodeId]
+	return ok && drain.State != pb.DrainState_DRAINED
+}
+
+// drainInProgress reports whether any drain is running or has failed and not
+// yet been retried. The caller must hold s.mu.
+func (s *NetworkVideoContentService) drainInProgress() bool {
+	for _, drain := range s.drains {
+		if drain.State != pb.DrainState_DRAINED {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *VideoContentAdminServer) DrainNode(ctx context.Context, req *pb.DrainNodeRequest) (*pb.DrainNodeResponse, error) {
+	nodeId := req.GetNodeAddress()
+	drain, files, err := s.nw.startDrain(ctx, nodeId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.nw.mu.RLock()
+	response := &pb.DrainNodeResponse{FileCount: drain.TotalFileCount, ByteCount: drain.TotalByteCount}
+	s.nw.mu.RUnlock()
+
+	// The drain outlives the call, keeping its request id and trace
+	go s.nw.drain(context.WithoutCancel(ctx), nodeId, files)
+
+	return response, nil
+}
+
+// startDrain takes a node out of the write ring and lists the files that have
+// to be copied off it. The node keeps serving reads until drain has copied
+// them and removed it.
+func (s *NetworkVideoContentService) startDrain(ctx context.Context, nodeId string) (*pb.DrainStatus, []*pb.FileInfo, error) {
+	s.mu.Lock()
+	if !slices.Contains(s.StorageServers, nodeId) {
+		s.mu.Unlock()
+		return nil, nil, status.Errorf(codes.NotFound, "node %s is not in the cluster", nodeId)
+	}
+	if drain, ok := s.drains[nodeId]; ok && drain.State == pb.DrainState_DRAINING {
+		s.mu.Unlock()
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "node %s is already draining", nodeId)
+	}
+	if len(s.Nodes) == 1 && s.Nodes[0].id == nodeId {
+		s.mu.Unlock()
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "cannot drain the last writable node in the cluster")
+	}
+
+	// Stop sending new writes to the node
+	if s.drains == nil {
+		s.drains = make(map[string]*pb.DrainStatus)
+	}
+	drain := &pb.DrainStatus{NodeAddress: nodeId, State: pb.DrainState_DRAINING}
+	s.drains[nodeId] = drain
+	s.initHashRing()
+	s.mu.Unlock()
+
+	files, err := s.listFiles(ctx, nodeId, nil)
+	if err != nil {
+		s.failDrain(nodeId, err)
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	for _, file := range files {
+		drain.TotalFileCount++
+		drain.TotalByteCount += file.GetSize()
+	}
+	drainRemainingBytes.WithLabelValues(nodeId).Set(float64(drain.TotalByteCount))
+	s.mu.Unlock()
+
+	return drain, files, nil
+}
+
+func (s *VideoContentAdminServer) GetClusterStatus(ctx context.Context, req *pb.GetClusterStatusRequest) (*pb.GetClusterStatusResponse, error) {
+	s.nw.mu.RLock()
+	defer s.nw.mu.RUnlock()
+
+	response := &pb.GetClusterStatusResponse{}
+	for _, storageServer := range s.nw.StorageServers {
+		if !s.nw.isDraining(storageServer) {
+			response.ActiveNodes = append(response.ActiveNodes, storageServer)
+		}
+	}
+	for _, drain := range s.nw.drains {
+		response.Drains = append(response.Drains, proto.Clone(drain).(*pb.DrainStatus))
+	}
+	slices.SortFunc(response.Drains, func(a, b *pb.DrainStatus) int {
+		return strings.Compare(a.NodeAddress, b.NodeAddress)
+	})
+
+	return response, nil
+}
+
+// drain copies a draining node's files to their new owners, then removes the
+// node from the cluster and deletes the copies it made. The node keeps serving reads
+// until it has been removed. It returns the number of files copied.
+func (s *NetworkVideoContentService) drain(ctx context.Context, nodeId string, files []*pb.FileInfo) (int32, error) {
+	// Group files by their new location
+	s.mu.RLock()
+	displacedFiles := make(map[string][]string)
+	for _, file := range files {
+		target := locateOnRing(s.Nodes, file.GetFileId())
+		displacedFiles[target] = append(displacedFiles[target], file.GetFileId())
+	}
+	s.mu.RUnlock()
+
+	// Copy the files over in batches so progress can be reported
+	var migrated int32
+	for target, fileIds := range displacedFiles {
+		for batch := range slices.Chunk(fileIds, drainBatchSize) {
+			count, bytes, err := s.transferFiles(ctx, nodeId, target, batch, true)
+			migrated += count
+			if err != nil {
+				s.failDrain(nodeId, err)
+				return migrated, err
+			}
+
+			s.mu.Lock()
+			s.drains[nodeId].MigratedFileCount += count
+			s.drains[nodeId].MigratedByteCount += bytes
+			drainRemainingBytes.WithLabelValues(nodeId).Set(float64(s.drains[nodeId].TotalByteCount - s.drains[nodeId].MigratedByteCount))
+			s.mu.Unlock()
+		}
+	}
+
+	// Every file has a verified copy on its new owner, so remove the node
+	s.mu.Lock()
+	if idx := slices.Index(s.StorageServers, nodeId); idx != -1 {
+		s.StorageServers = slices.Delete(s.StorageServers, idx, idx+1)
+	}
+	s.drains[nodeId].State = pb.DrainState_DRAINED
+	drainRemainingBytes.DeleteLabelValues(nodeId)
+	s.initHashRing()
+	s.mu.Unlock()
+	slog.InfoContext(ctx, "Drained node", "node", nodeId)
+
+	// Clean up the copies left on the removed node
+	err := s.deleteFiles(ctx, nodeId, files)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while deleting files from drained node", "node", nodeId, "err", err)
+	}
+	return migrated, nil
+}
+
+// failDrain records that a drain stopped early. The node stays out of the
+// write ring and keeps serving reads until the drain is retried.
+func (s *NetworkVideoContentService) failDrain(nodeId string, err error) {
+	slog.Error("Error while draining node", "node", nodeId, "err", err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drains[nodeId].State = pb.DrainState_DRAIN_FAILED
+	s.drains[nodeId].Error = err.Error()
+}
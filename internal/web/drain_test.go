@@ -0,0 +1,172 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	pb "tritontube/internal/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDrainNode(t *testing.T) {
+	ctx := context.Background()
+	first, second, third := startStorageNode(t), startStorageNode(t), startStorageNode(t)
+	admin := startService(t, first, second, third)
+	nw := admin.nw
+
+	for i := range 6 {
+		err := nw.Write(ctx, fmt.Sprintf("video%d", i), "manifest.mpd", []byte("manifest"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := nw.listFiles(ctx, first, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := admin.DrainNode(ctx, &pb.DrainNodeRequest{NodeAddress: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.GetFileCount() != int32(len(files)) || response.GetByteCount() != int64(len(files)*len("manifest")) {
+		t.Errorf("draining %d files reported %d files and %d bytes", len(files), response.GetFileCount(), response.GetByteCount())
+	}
+
+	// Wait for the drain to finish in the background
+	var drain *pb.DrainStatus
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		clusterStatus, err := admin.GetClusterStatus(ctx, &pb.GetClusterStatusRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		drain = clusterStatus.GetDrains()[0]
+		if drain.GetState() != pb.DrainState_DRAINING || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if drain.GetState() != pb.DrainState_DRAINED {
+		t.Fatalf("drain ended in state %v: %s", drain.GetState(), drain.GetError())
+	}
+	if drain.GetMigratedFileCount() != int32(len(files)) {
+		t.Errorf("drain migrated %d files, want %d", drain.GetMigratedFileCount(), len(files))
+	}
+
+	if slices.Contains(nw.StorageServers, first) {
+		t.Errorf("%s is still in the cluster after draining", first)
+	}
+	for i := range 6 {
+		data, err := nw.Read(ctx, fmt.Sprintf("video%d", i), "manifest.mpd")
+		if err != nil || string(data) != "manifest" {
+			t.Errorf("reading video%d after the drain = %q, %v", i, data, err)
+		}
+	}
+
+	// The copies left behind are deleted once the node is out of the cluster
+	var left []*pb.FileInfo
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		left, err = nw.listFiles(ctx, first, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left) == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(left) != 0 {
+		t.Errorf("the drained node still holds %d files", len(left))
+	}
+
+	// The node can join again once drained
+	_, err = admin.AddNode(ctx, &pb.AddNodeRequest{NodeAddress: first})
+	if err != nil {
+		t.Errorf("adding the drained node back: %v", err)
+	}
+}
+
+func TestDrainKeepsLaterWrites(t *testing.T) {
+	ctx := context.Background()
+	first, second := startStorageNode(t), startStorageNode(t)
+	admin := startService(t, first, second)
+	nw := admin.nw
+
+	err := nw.writeToNode(ctx, first, "video", "manifest.mpd", []byte("manifest"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, files, err := nw.startDrain(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(nw.Nodes, func(node Node) bool { return node.id == first }) {
+		t.Errorf("%s still takes writes while draining", first)
+	}
+
+	// A write that was already on its way lands after the files were listed
+	err = nw.writeToNode(ctx, first, "video", "segment.m4s", []byte("segment"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = nw.drain(ctx, first, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	left, err := nw.listFiles(ctx, first, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].GetFileId() != "video/segment.m4s" {
+		t.Errorf("the drained node holds %v, want only the later write", left)
+	}
+}
+
+func TestDrainRefusals(t *testing.T) {
+	ctx := context.Background()
+	first, second := startStorageNode(t), startStorageNode(t)
+	admin := startService(t, first, second)
+	nw := admin.nw
+
+	_, _, err := nw.startDrain(ctx, "127.0.0.1:1")
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("draining a node not in the cluster = %v, want NotFound", err)
+	}
+	_, _, err = nw.startDrain(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing else may change the cluster while the drain is running or after
+	// it failed
+	for _, state := range []string{"running", "failed"} {
+		_, err = admin.AddNode(ctx, &pb.AddNodeRequest{NodeAddress: startStorageNode(t)})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("AddNode with a %s drain = %v, want FailedPrecondition", state, err)
+		}
+		_, err = admin.RemoveNode(ctx, &pb.RemoveNodeRequest{NodeAddress: second})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("RemoveNode with a %s drain = %v, want FailedPrecondition", state, err)
+		}
+		_, _, err = nw.startDrain(ctx, second)
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("draining the last writable node with a %s drain = %v, want FailedPrecondition", state, err)
+		}
+		nw.failDrain(first, fmt.Errorf("test failure"))
+	}
+	// A failed drain can be retried, but not started twice
+	_, _, err = nw.startDrain(ctx, first)
+	if err != nil {
+		t.Fatalf("retrying a failed drain: %v", err)
+	}
+	_, _, err = nw.startDrain(ctx, first)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("draining a node twice = %v, want FailedPrecondition", err)
+	}
+}
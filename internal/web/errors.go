@@ -0,0 +1,58 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"tritontube/internal/fileid"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Errors returned by the content services. They are wrapped with details, so
+// compare against them with errors.Is.
+var (
+	ErrNotFound        = errors.New("content not found")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrStorageFull     = errors.New("storage full")
+	ErrUnavailable     = errors.New("storage unavailable")
+)
+
+// fromStatus translates an error returned by a storage node into one of the
+// errors above, keeping the original message.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch status.Code(err) {
+	case codes.NotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, status.Convert(err).Message())
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %s", ErrInvalidArgument, status.Convert(err).Message())
+	case codes.ResourceExhausted:
+		return fmt.Errorf("%w: %s", ErrStorageFull, status.Convert(err).Message())
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %s", ErrUnavailable, status.Convert(err).Message())
+	default:
+		return err
+	}
+}
+
+// httpStatus picks the HTTP status code to answer with for an error from a
+// content or metadata service.
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidArgument), errors.Is(err, fileid.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrStorageFull):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tritontube/internal/fileid"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusToHTTP(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want error
+		http int
+	}{
+		{codes.NotFound, ErrNotFound, http.StatusNotFound},
+		{codes.InvalidArgument, ErrInvalidArgument, http.StatusBadRequest},
+		{codes.ResourceExhausted, ErrStorageFull, http.StatusInsufficientStorage},
+		{codes.Unavailable, ErrUnavailable, http.StatusServiceUnavailable},
+		{codes.DeadlineExceeded, ErrUnavailable, http.StatusServiceUnavailable},
+		{codes.DataLoss, nil, http.StatusInternalServerError},
+		{codes.Internal, nil, http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		err := fromStatus(status.Error(test.code, "video/manifest.mpd"))
+		if test.want != nil && !errors.Is(err, test.want) {
+			t.Errorf("fromStatus(%v) = %v, want %v", test.code, err, test.want)
+		}
+		if !strings.Contains(err.Error(), "video/manifest.mpd") {
+			t.Errorf("fromStatus(%v) = %v, lost the message", test.code, err)
+		}
+		// Wrapping, as the services do, keeps the status
+		if got := httpStatus(fmt.Errorf("reading video: %w", err)); got != test.http {
+			t.Errorf("httpStatus for %v = %d, want %d", test.code, got, test.http)
+		}
+	}
+
+	if err := fromStatus(nil); err != nil {
+		t.Errorf("fromStatus(nil) = %v", err)
+	}
+	if got := httpStatus(fmt.Errorf("%w: ..", fileid.ErrInvalid)); got != http.StatusBadRequest {
+		t.Errorf("httpStatus for an invalid file id = %d, want %d", got, http.StatusBadRequest)
+	}
+}
+
+// failingContentService fails every read with the same error.
+type failingContentService struct {
+	VideoContentService
+	err error
+}
+
+func (s failingContentService) Read(ctx context.Context, videoId string, filename string) ([]byte, error) {
+	return nil, s.err
+}
+
+func TestContentErrorStatus(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.NotFound, http.StatusNotFound},
+		{codes.ResourceExhausted, http.StatusInsufficientStorage},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DataLoss, http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		s := NewServer(nil, failingContentService{err: fromStatus(status.Error(test.code, "node says no"))})
+		recorder := httptest.NewRecorder()
+		s.handleVideoContent(recorder, httptest.NewRequest(http.MethodGet, "/content/video/manifest.mpd", nil))
+		if recorder.Code != test.want {
+			t.Errorf("GET with a storage node failing with %v = %d, want %d", test.code, recorder.Code, test.want)
+		}
+	}
+}
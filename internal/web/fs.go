@@ -3,9 +3,16 @@
 package web
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
-	"path"
+	"syscall"
+	"tritontube/internal/atomicfile"
+	"tritontube/internal/fileid"
 )
 
 // FSVideoContentService implements VideoContentService using the local filesystem.
@@ -13,34 +20,123 @@ type FSVideoContentService struct{
 	FSDir string
 }
 
-func (s FSVideoContentService) Read(videoId string, filename string) ([]byte, error) {
-	readData, err := os.ReadFile(path.Join(s.FSDir, videoId, filename))
+func (s FSVideoContentService) Read(ctx context.Context, videoId string, filename string) ([]byte, error) {
+	err := fileid.Validate(videoId, filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	// Resolve paths inside FSDir only, so that they can never escape it
+	root, err := os.OpenRoot(s.FSDir)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, videoId, filename)
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error while opening content directory", "video_id", videoId, "file", filename, "err", err)
+		return nil, err
+	}
+	defer root.Close()
+
+	file, err := root.Open(fileid.Join(videoId, filename))
 	if os.IsNotExist(err) {
-		return nil, nil
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, videoId, filename)
 	} else if err != nil {
-		log.Printf("Error while reading from file: %v", err)
+		slog.ErrorContext(ctx, "Error while reading from file", "video_id", videoId, "file", filename, "err", err)
+		return nil, err
+	}
+	defer file.Close()
+
+	readData, err := io.ReadAll(file)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while reading from file", "video_id", videoId, "file", filename, "err", err)
 		return nil, err
 	}
 	return readData, nil
 }
 
-func (s FSVideoContentService) Write(videoId string, filename string, data []byte) error {
-	dirName := path.Join(s.FSDir, videoId)
+func (s FSVideoContentService) Write(ctx context.Context, videoId string, filename string, data []byte) error {
+	err := fileid.Validate(videoId, filename)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	err = os.MkdirAll(s.FSDir, 0755)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while creating directory", "video_id", videoId, "file", filename, "err", err)
+		return err
+	}
 
-	err := os.MkdirAll(dirName, 0755)
+	root, err := os.OpenRoot(s.FSDir)
 	if err != nil {
-		log.Printf("Error while creating directory: %v", err)
+		slog.ErrorContext(ctx, "Error while opening content directory", "video_id", videoId, "file", filename, "err", err)
+		return err
+	}
+	defer root.Close()
+
+	err = root.Mkdir(videoId, 0755)
+	if err == nil {
+		err = atomicfile.SyncDir(root, ".")
+	}
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		slog.ErrorContext(ctx, "Error while creating directory", "video_id", videoId, "file", filename, "err", err)
+		return err
+	}
+
+	err = atomicfile.WriteFile(root, fileid.Join(videoId, filename), data, 0644)
+	if errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EDQUOT) {
+		return fmt.Errorf("%w: %v", ErrStorageFull, err)
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error while writing to file", "video_id", videoId, "file", filename, "err", err)
 		return err
 	}
 
-	err = os.WriteFile(path.Join(dirName, filename), data, 0644)
+	return nil
+}
+
+// Delete removes every file of a video. The video directory is renamed out of
+// the way first, so that readers never see it half deleted.
+func (s FSVideoContentService) Delete(ctx context.Context, videoId string) error {
+	err := fileid.ValidateName(videoId)
 	if err != nil {
-		log.Printf("Error while writing to file: %v", err)
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+
+	root, err := os.OpenRoot(s.FSDir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, videoId)
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error while opening content directory", "video_id", videoId, "err", err)
+		return err
+	}
+	defer root.Close()
+
+	err = atomicfile.RemoveDir(root, videoId)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, videoId)
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Error while deleting video", "video_id", videoId, "err", err)
 		return err
 	}
 
 	return nil
 }
 
+// CleanTempFiles removes temporary files left behind by writes and deletes
+// that were interrupted by a crash. It should be called before the server starts.
+func (s FSVideoContentService) CleanTempFiles() error {
+	root, err := os.OpenRoot(s.FSDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	removed, err := atomicfile.CleanTemp(root)
+	if removed > 0 {
+		slog.Info("Removed leftover temporary files", "count", removed)
+	}
+	return err
+}
+
 // Uncomment the following line to ensure FSVideoContentService implements VideoContentService
 var _ VideoContentService = (*FSVideoContentService)(nil)
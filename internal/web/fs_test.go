@@ -0,0 +1,124 @@
+package web
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// escapeSeeds are video ids and filenames that try to reach outside the
+// content directory.
+var escapeSeeds = [][2]string{
+	{"video", "manifest.mpd"},
+	{"..", "outside/secret"},
+	{"video/../..", "outside"},
+	{"/etc", "passwd"},
+	{"", "/etc/passwd"},
+	{"video", ".."},
+	{"link", "secret"},
+	{"video", "link"},
+	{"video", "file\x00.m4s"},
+	{"video\x00", "file"},
+	{".hidden", "file"},
+}
+
+// escapeTree creates a content directory and a directory outside it holding a
+// secret, with symlinks from the content directory to the secret.
+func escapeTree(t *testing.T) (string, string) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(base, "video"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(base, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(base, "video", "link")); err != nil {
+		t.Fatal(err)
+	}
+	return base, outside
+}
+
+// checkOutside fails the test if anything outside the content directory
+// changed.
+func checkOutside(t *testing.T, outside string) {
+	t.Helper()
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "secret" {
+		t.Fatalf("files outside the content directory changed: %v", entries)
+	}
+	data, err := os.ReadFile(filepath.Join(outside, "secret"))
+	if err != nil || string(data) != "secret" {
+		t.Fatalf("secret outside the content directory changed: %q, %v", data, err)
+	}
+}
+
+// unsafeName reports whether a video id or filename is one that must always
+// be rejected.
+func unsafeName(name string) bool {
+	return name == "" || strings.HasPrefix(name, "/") || strings.ContainsRune(name, 0) || slices.Contains(strings.Split(name, "/"), "..")
+}
+
+func FuzzFSFileId(f *testing.F) {
+	for _, seed := range escapeSeeds {
+		f.Add(seed[0], seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, videoId string, filename string) {
+		base, outside := escapeTree(t)
+		service := FSVideoContentService{FSDir: base}
+		ctx := context.Background()
+
+		writeErr := service.Write(ctx, videoId, filename, []byte("fuzz"))
+		checkOutside(t, outside)
+
+		data, readErr := service.Read(ctx, videoId, filename)
+		if readErr == nil && string(data) == "secret" {
+			t.Fatalf("read %q/%q returned the secret", videoId, filename)
+		}
+
+		deleteErr := service.Delete(ctx, videoId)
+		checkOutside(t, outside)
+
+		if unsafeName(videoId) || unsafeName(filename) {
+			if writeErr == nil {
+				t.Errorf("Write accepted unsafe file %q/%q", videoId, filename)
+			}
+			if readErr == nil {
+				t.Errorf("Read accepted unsafe file %q/%q", videoId, filename)
+			}
+		}
+		if unsafeName(videoId) && deleteErr == nil {
+			t.Errorf("Delete accepted unsafe video id %q", videoId)
+		}
+	})
+}
+
+func TestFSSymlinksAreNotFollowed(t *testing.T) {
+	base, outside := escapeTree(t)
+	service := FSVideoContentService{FSDir: base}
+	ctx := context.Background()
+
+	if _, err := service.Read(ctx, "link", "secret"); err == nil {
+		t.Errorf("Read followed a symlinked video directory")
+	}
+	if _, err := service.Read(ctx, "video", "link"); err == nil {
+		t.Errorf("Read followed a symlinked file")
+	}
+	if err := service.Write(ctx, "link", "secret", []byte("fuzz")); err == nil {
+		t.Errorf("Write followed a symlinked video directory")
+	}
+	if err := service.Delete(ctx, "link"); err == nil {
+		t.Errorf("Delete followed a symlinked video directory")
+	}
+	checkOutside(t, outside)
+}
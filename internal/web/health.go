@@ -0,0 +1,195 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	pb "tritontube/internal/proto"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultHealthCheckInterval         = 5 * time.Second
+	defaultHealthCheckFailureThreshold = 3
+	healthCheckTimeout                 = 2 * time.Second
+)
+
+// nodeHealth tracks the probe results for one storage node. A node that fails
+// a probe becomes suspect, and is marked down once it has failed
+// HealthCheckFailureThreshold probes in a row. A single successful probe
+// brings it back up.
+type nodeHealth struct {
+	health              pb.NodeHealth
+	consecutiveFailures int
+	lastError           string
+	// full is set while the node reports it has no room for more content
+	full bool
+}
+
+// startHealthChecks probes every storage node in the background until the
+// service is shut down.
+func (s *NetworkVideoContentService) startHealthChecks() {
+	interval := s.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.checkHealth()
+			select {
+			case <-ticker.C:
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// checkHealth probes every storage node once, in parallel.
+func (s *NetworkVideoContentService) checkHealth() {
+	s.mu.RLock()
+	storageServers := slices.Clone(s.StorageServers)
+	s.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, nodeId := range storageServers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			full, err := s.probe(nodeId)
+			s.recordHealth(nodeId, err)
+			if err == nil {
+				s.recordFull(nodeId, full)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// probe asks a storage node's gRPC health service whether it is serving, and
+// the node whether it is full.
+func (s *NetworkVideoContentService) probe(nodeId string) (bool, error) {
+	conn, err := s.dialNode(nodeId)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return false, err
+	}
+	if response.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return false, fmt.Errorf("node reported %v", response.GetStatus())
+	}
+
+	capacity, err := pb.NewNetworkVideoContentClient(conn).Capacity(ctx, &pb.CapacityRequest{})
+	if err != nil {
+		return false, err
+	}
+
+	return capacity.GetFull(), nil
+}
+
+// recordHealth moves a node through the up/suspect/down states based on the
+// outcome of a probe or request.
+func (s *NetworkVideoContentService) recordHealth(nodeId string, err error) {
+	threshold := s.HealthCheckFailureThreshold
+	if threshold <= 0 {
+		threshold = defaultHealthCheckFailureThreshold
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.health == nil {
+		s.health = make(map[string]*nodeHealth)
+	}
+	state, ok := s.health[nodeId]
+	if !ok {
+		state = &nodeHealth{}
+		s.health[nodeId] = state
+	}
+
+	if err == nil {
+		if state.health != pb.NodeHealth_UP {
+			slog.Info("Storage node is up", "node", nodeId)
+		}
+		state.health = pb.NodeHealth_UP
+		state.consecutiveFailures = 0
+		state.lastError = ""
+		return
+	}
+
+	state.consecutiveFailures++
+	state.lastError = err.Error()
+	if state.consecutiveFailures >= threshold {
+		if state.health != pb.NodeHealth_DOWN {
+			slog.Warn("Storage node is down", "node", nodeId, "err", err)
+		}
+		state.health = pb.NodeHealth_DOWN
+	} else if state.health != pb.NodeHealth_DOWN {
+		state.health = pb.NodeHealth_SUSPECT
+	}
+}
+
+// recordFull notes whether a node has room for more content, so that writes
+// skip it while it is full.
+func (s *NetworkVideoContentService) recordFull(nodeId string, full bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.health == nil {
+		s.health = make(map[string]*nodeHealth)
+	}
+	state, ok := s.health[nodeId]
+	if !ok {
+		state = &nodeHealth{}
+		s.health[nodeId] = state
+	}
+
+	if full && !state.full {
+		slog.Warn("Storage node is full", "node", nodeId)
+	} else if !full && state.full {
+		slog.Info("Storage node has room again", "node", nodeId)
+	}
+	state.full = full
+}
+
+// isFull reports whether a node was last known to be full. The caller must
+// hold s.mu.
+func (s *NetworkVideoContentService) isFull(nodeId string) bool {
+	state, ok := s.health[nodeId]
+	return ok && state.full
+}
+
+// isDown reports whether a node has been marked down. Nodes that have not
+// been probed yet are assumed to be up. The caller must hold s.mu.
+func (s *NetworkVideoContentService) isDown(nodeId string) bool {
+	state, ok := s.health[nodeId]
+	return ok && state.health == pb.NodeHealth_DOWN
+}
+
+// nodeInfo describes a node's health for the admin server. The caller must
+// hold s.mu.
+func (s *NetworkVideoContentService) nodeInfo(nodeId string) *pb.NodeInfo {
+	info := &pb.NodeInfo{NodeAddress: nodeId}
+	if state, ok := s.health[nodeId]; ok {
+		info.Health = state.health
+		info.ConsecutiveFailures = int32(state.consecutiveFailures)
+		info.LastError = state.lastError
+		info.Full = state.full
+	}
+	return info
+}
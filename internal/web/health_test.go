@@ -0,0 +1,130 @@
+package web
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	pb "tritontube/internal/proto"
+	"tritontube/internal/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestRecordHealth(t *testing.T) {
+	nw := &NetworkVideoContentService{HealthCheckFailureThreshold: 3}
+	probeErr := errors.New("connection refused")
+
+	nw.mu.RLock()
+	down, info := nw.isDown("node"), nw.nodeInfo("node")
+	nw.mu.RUnlock()
+	if down || info.GetHealth() != pb.NodeHealth_HEALTH_UNKNOWN {
+		t.Errorf("a node never probed is %v and down = %v, want HEALTH_UNKNOWN and not down", info.GetHealth(), down)
+	}
+
+	steps := []struct {
+		err      error
+		health   pb.NodeHealth
+		failures int32
+	}{
+		{nil, pb.NodeHealth_UP, 0},
+		{probeErr, pb.NodeHealth_SUSPECT, 1},
+		{probeErr, pb.NodeHealth_SUSPECT, 2},
+		{probeErr, pb.NodeHealth_DOWN, 3},
+		{probeErr, pb.NodeHealth_DOWN, 4},
+		{nil, pb.NodeHealth_UP, 0},
+		{probeErr, pb.NodeHealth_SUSPECT, 1},
+	}
+	for i, step := range steps {
+		nw.recordHealth("node", step.err)
+
+		nw.mu.RLock()
+		down, info := nw.isDown("node"), nw.nodeInfo("node")
+		nw.mu.RUnlock()
+		if info.GetHealth() != step.health || info.GetConsecutiveFailures() != step.failures {
+			t.Errorf("after step %d the node is %v with %d failures, want %v with %d", i, info.GetHealth(), info.GetConsecutiveFailures(), step.health, step.failures)
+		}
+		if down != (step.health == pb.NodeHealth_DOWN) {
+			t.Errorf("after step %d down = %v with the node %v", i, down, info.GetHealth())
+		}
+		var wantErr string
+		if step.err != nil {
+			wantErr = step.err.Error()
+		}
+		if info.GetLastError() != wantErr {
+			t.Errorf("after step %d the last error is %q, want %q", i, info.GetLastError(), wantErr)
+		}
+	}
+}
+
+func TestRecordHealthDefaultThreshold(t *testing.T) {
+	nw := &NetworkVideoContentService{}
+	for i := range defaultHealthCheckFailureThreshold {
+		nw.mu.RLock()
+		down := nw.isDown("node")
+		nw.mu.RUnlock()
+		if down {
+			t.Fatalf("the node is down after %d failures, want %d", i, defaultHealthCheckFailureThreshold)
+		}
+		nw.recordHealth("node", errors.New("timed out"))
+	}
+	nw.mu.RLock()
+	defer nw.mu.RUnlock()
+	if !nw.isDown("node") {
+		t.Errorf("the node is up after %d failures", defaultHealthCheckFailureThreshold)
+	}
+}
+
+func TestReadsTryDownNodesLast(t *testing.T) {
+	nw := &NetworkVideoContentService{StorageServers: []string{"first:8090", "second:8090", "third:8090"}, HealthCheckFailureThreshold: 1}
+	nw.initHashRing()
+
+	owner := nw.getNWReadLocations("video", "manifest.mpd")[0]
+	nw.recordHealth(owner, errors.New("connection refused"))
+	locations := nw.getNWReadLocations("video", "manifest.mpd")
+	if len(locations) != 3 || locations[2] != owner {
+		t.Errorf("read locations with the owner %s down = %v, want it last", owner, locations)
+	}
+
+	nw.recordHealth(owner, nil)
+	if locations := nw.getNWReadLocations("video", "manifest.mpd"); locations[0] != owner {
+		t.Errorf("read locations with the owner %s back up = %v, want it first", owner, locations)
+	}
+}
+
+// startProbedNode serves a storage node reporting the given health status,
+// returning its address.
+func startProbedNode(t *testing.T, servingStatus healthpb.HealthCheckResponse_ServingStatus) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	pb.RegisterNetworkVideoContentServer(gs, &storage.NetworkVideoContentServer{Dir: t.TempDir()})
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", servingStatus)
+	healthpb.RegisterHealthServer(gs, healthServer)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+	return lis.Addr().String()
+}
+
+func TestCheckHealth(t *testing.T) {
+	serving := startProbedNode(t, healthpb.HealthCheckResponse_SERVING)
+	notServing := startProbedNode(t, healthpb.HealthCheckResponse_NOT_SERVING)
+	unreachable := "127.0.0.1:1"
+	nw := &NetworkVideoContentService{StorageServers: []string{serving, notServing, unreachable}, HealthCheckFailureThreshold: 1}
+
+	nw.checkHealth()
+
+	nw.mu.RLock()
+	defer nw.mu.RUnlock()
+	for nodeId, want := range map[string]pb.NodeHealth{serving: pb.NodeHealth_UP, notServing: pb.NodeHealth_DOWN, unreachable: pb.NodeHealth_DOWN} {
+		if info := nw.nodeInfo(nodeId); info.GetHealth() != want {
+			t.Errorf("%s is %v after a probe (%s), want %v", nodeId, info.GetHealth(), info.GetLastError(), want)
+		}
+	}
+}
@@ -1,19 +1,28 @@
 package web
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type VideoMetadata struct {
 	Id         string
 	UploadedAt time.Time
 }
 
+// The services take the context of the request they serve, which carries its
+// request id and deadline.
+
 type VideoMetadataService interface {
-	Read(id string) (*VideoMetadata, error)
-	List() ([]VideoMetadata, error)
-	Create(videoId string, uploadedAt time.Time) error
+	Read(ctx context.Context, id string) (*VideoMetadata, error)
+	List(ctx context.Context) ([]VideoMetadata, error)
+	Create(ctx context.Context, videoId string, uploadedAt time.Time) error
+	// Delete removes the metadata of a video, if there is any.
+	Delete(ctx context.Context, videoId string) error
 }
 
 type VideoContentService interface {
-	Read(videoId string, filename string) ([]byte, error)
-	Write(videoId string, filename string, data []byte) error
+	Read(ctx context.Context, videoId string, filename string) ([]byte, error)
+	Write(ctx context.Context, videoId string, filename string, data []byte) error
+	Delete(ctx context.Context, videoId string) error
 }
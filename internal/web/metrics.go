@@ -0,0 +1,110 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tritontube/internal/logging"
+	"tritontube/internal/tracing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Uploads and transcodes take far longer than other requests.
+var slowBuckets = prometheus.ExponentialBuckets(0.5, 2, 12)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tritontube_http_requests_total",
+		Help: "HTTP requests served, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "tritontube_http_request_duration_seconds",
+		Help: "Time taken to serve HTTP requests, by route and method.",
+	}, []string{"route", "method"})
+
+	uploadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "tritontube_upload_duration_seconds",
+		Help:    "Time taken to process an upload, from receiving it to storing every file.",
+		Buckets: slowBuckets,
+	})
+
+	transcodeDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "tritontube_transcode_duration_seconds",
+		Help:    "Time ffmpeg took to convert an upload to MPEG-DASH.",
+		Buckets: slowBuckets,
+	})
+
+	contentBytesServed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tritontube_content_bytes_served_total",
+		Help: "Bytes of video content sent to viewers.",
+	})
+
+	ringNodes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tritontube_ring_nodes",
+		Help: "Storage nodes on the hash ring, by whether they take writes or are draining.",
+	}, []string{"state"})
+
+	migratedFiles = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tritontube_migrated_files_total",
+		Help: "Files moved between storage nodes while rebalancing.",
+	})
+
+	migratedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tritontube_migrated_bytes_total",
+		Help: "Bytes moved between storage nodes while rebalancing.",
+	})
+
+	drainRemainingBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tritontube_drain_remaining_bytes",
+		Help: "Bytes still to be copied off each node being drained.",
+	}, []string{"node"})
+
+	contentCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tritontube_content_cache_requests_total",
+		Help: "Content reads from the cache, by result: hit, miss, or shared for misses that joined a read already in progress.",
+	}, []string{"result"})
+
+	metadataCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tritontube_metadata_cache_requests_total",
+		Help: "Metadata reads and lists from the cache, by method and result: hit or miss.",
+	}, []string{"method", "result"})
+
+	contentCacheBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "tritontube_content_cache_bytes",
+		Help: "Bytes of video content held in the cache.",
+	})
+)
+
+// statusRecorder remembers the status code a handler answered with.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// instrument gives every request served by a handler under route a request
+// id and a trace span, then counts, times and logs it. A request id sent by the client in
+// X-Request-Id is kept, and the id is sent back in the same header.
+func instrument(route string, handler http.Handler) http.Handler {
+	return tracing.Handler(route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		ctx := logging.WithRequestID(r.Context(), r.Header.Get("X-Request-Id"))
+		w.Header().Set("X-Request-Id", logging.RequestID(ctx))
+		recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		handler.ServeHTTP(recorder, r.WithContext(ctx))
+
+		duration := time.Since(started)
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.code)).Inc()
+		httpRequestDuration.WithLabelValues(route, r.Method).Observe(duration.Seconds())
+		slog.DebugContext(ctx, "Served request", "method", r.Method, "path", r.URL.Path, "status", recorder.code, "duration", duration)
+	}))
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "tritontube/internal/proto"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestInstrumentCountsAndTimesRequests(t *testing.T) {
+	handler := instrument("/test/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "teapot", http.StatusTeapot)
+	}))
+
+	for range 3 {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test/anything", nil))
+		if recorder.Header().Get("X-Request-Id") == "" {
+			t.Errorf("response has no request id")
+		}
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/test/anything", nil))
+
+	if count := testutil.ToFloat64(httpRequests.WithLabelValues("/test/", "GET", "418")); count != 3 {
+		t.Errorf("counted %v GET requests with status 418, want 3", count)
+	}
+	if count := testutil.ToFloat64(httpRequests.WithLabelValues("/test/", "POST", "418")); count != 1 {
+		t.Errorf("counted %v POST requests with status 418, want 1", count)
+	}
+
+	var metric dto.Metric
+	if err := httpRequestDuration.WithLabelValues("/test/", "GET").(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	if count := metric.GetHistogram().GetSampleCount(); count != 3 {
+		t.Errorf("timed %d GET requests, want 3", count)
+	}
+}
+
+func TestRingNodesGauge(t *testing.T) {
+	nw := &NetworkVideoContentService{StorageServers: []string{"node1:8090", "node2:8090", "node3:8090"}}
+	nw.drains = map[string]*pb.DrainStatus{"node2:8090": {NodeAddress: "node2:8090", State: pb.DrainState_DRAINING}}
+	nw.initHashRing()
+
+	if count := testutil.ToFloat64(ringNodes.WithLabelValues("writable")); count != 2 {
+		t.Errorf("ring reports %v writable nodes, want 2", count)
+	}
+	if count := testutil.ToFloat64(ringNodes.WithLabelValues("draining")); count != 1 {
+		t.Errorf("ring reports %v draining nodes, want 1", count)
+	}
+}
+
+func TestMigrationCounters(t *testing.T) {
+	ctx := context.Background()
+	source, destination := startStorageNode(t), startStorageNode(t)
+	nw := &NetworkVideoContentService{StorageServers: []string{source, destination}}
+	for _, filename := range []string{"a", "bb"} {
+		if err := nw.writeToNode(ctx, source, "video", filename, []byte(filename), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, bytes := testutil.ToFloat64(migratedFiles), testutil.ToFloat64(migratedBytes)
+	if _, _, err := nw.transferFiles(ctx, source, destination, []string{"video/a", "video/bb"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if count := testutil.ToFloat64(migratedFiles) - files; count != 2 {
+		t.Errorf("counted %v migrated files, want 2", count)
+	}
+	if count := testutil.ToFloat64(migratedBytes) - bytes; count != 3 {
+		t.Errorf("counted %v migrated bytes, want 3", count)
+	}
+}
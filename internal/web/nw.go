@@ -3,24 +3,38 @@
 package web
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
-	"encoding/binary"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net"
 	"slices"
 	"sort"
 	"strings"
-
+	"sync"
+	"time"
+
+	"tritontube/internal/fileid"
+	"tritontube/internal/hashring"
+	"tritontube/internal/logging"
+	"tritontube/internal/metrics"
+	"tritontube/internal/tracing"
 	pb "tritontube/internal/proto"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 func hashStringToUint64(s string) uint64 {
-	sum := sha256.Sum256([]byte(s))
-	return binary.BigEndian.Uint64(sum[:8])
+	return hashring.Hash(s)
 }
 
 // NetworkVideoContentService implements VideoContentService using a network of nodes.
@@ -35,225 +49,810 @@ type VideoContentAdminServer struct {
 }
 
 func (s *VideoContentAdminServer) AddNode(ctx context.Context, req *pb.AddNodeRequest) (*pb.AddNodeResponse, error) {
-	// Add node to hash ring
-	s.nw.StorageServers = append(s.nw.StorageServers, req.GetNodeAddress())
-	s.nw.initHashRing()
-
-	// Find nextNode
-	var nextNode Node
-	for idx, node := range s.nw.Nodes {
-		if node.id == req.GetNodeAddress() {
-			if idx + 1 == len(s.nw.Nodes) {
-				nextNode = s.nw.Nodes[0]
-			} else {
-				nextNode = s.nw.Nodes[idx+1]
-			}
-			break
-		}
+	s.nw.mu.Lock()
+	if s.nw.drainInProgress() {
+		s.nw.mu.Unlock()
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot add a node while a drain is in progress")
 	}
 
-	// Open connection to nextNode
-	conn, err := grpc.NewClient(nextNode.id, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, err
+	if slices.Contains(s.nw.StorageServers, req.GetNodeAddress()) {
+		s.nw.mu.Unlock()
+		return nil, status.Errorf(codes.AlreadyExists, "node %s is already in the cluster", req.GetNodeAddress())
 	}
-	client := pb.NewNetworkVideoContentClient(conn)
 
-	// Find out which files need to be displaced
-	response, err := client.List(context.Background(), &pb.ListRequest{})
-	if err != nil {
-		return nil, err
-	}
-	filesOnNextNode := response.GetFileIds()
-	var displacedFiles []string
-	for _, file := range filesOnNextNode {
-		if s.nw.getNWLocation(strings.Split(file, "/")[0], strings.Split(file, "/")[1]) == req.GetNodeAddress() {
-			displacedFiles = append(displacedFiles, file)
-		}
-	}
+	// Add node to hash ring
+	s.nw.StorageServers = append(s.nw.StorageServers, req.GetNodeAddress())
+	s.nw.initHashRing()
 
-	// Move displaced files to new hash ring
-	for _, file := range displacedFiles {
-		// Read file
-		response, err := client.Read(context.Background(), &pb.ReadRequest{
-			FileId: file,
-		})
+	// Writes spill over from a full owner to the nodes after it, so the
+	// displaced files may be on any of the other nodes. Go round the ring
+	// from the new node's successor, which held its range until now.
+	idx := slices.IndexFunc(s.nw.Nodes, func(node Node) bool { return node.id == req.GetNodeAddress() })
+	var holders []string
+	for i := 1; i < len(s.nw.Nodes); i++ {
+		holders = append(holders, s.nw.Nodes[(idx + i) % len(s.nw.Nodes)].id)
+	}
+	hashRange := ownedRange(s.nw.Nodes, req.GetNodeAddress())
+	s.nw.mu.Unlock()
+
+	// Only the files in the new node's part of the ring need to be displaced.
+	// A file on several nodes is taken from the first, which is the copy
+	// reads found until now; the others stay behind it as before.
+	var migrated int32
+	seen := make(map[string]bool)
+	for _, nodeId := range holders {
+		files, err := s.nw.listFiles(ctx, nodeId, hashRange)
 		if err != nil {
 			return nil, err
 		}
+		var displacedFiles []string
+		for _, file := range files {
+			if !seen[file.GetFileId()] {
+				seen[file.GetFileId()] = true
+				displacedFiles = append(displacedFiles, file.GetFileId())
+			}
+		}
 
-		// Write file to NW
-		s.nw.Write(strings.Split(file, "/")[0], strings.Split(file, "/")[1], response.GetData())
-
-		// Delete file from old node
-		_, err = client.Delete(context.Background(), &pb.DeleteRequest{FileId: file})
+		// Have the node push its displaced files to the new node
+		count, _, err := s.nw.transferFiles(ctx, nodeId, req.GetNodeAddress(), displacedFiles, false)
+		migrated += count
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return &pb.AddNodeResponse{MigratedFileCount: int32(len(displacedFiles))}, nil
+	return &pb.AddNodeResponse{MigratedFileCount: migrated}, nil
 }
 
+// RemoveNode drains a node and waits for the drain to finish. The node keeps
+// serving reads for its files until they have been copied to their new owners.
 func (s *VideoContentAdminServer) RemoveNode(ctx context.Context, req *pb.RemoveNodeRequest) (*pb.RemoveNodeResponse, error) {
-	// Find node index
-	var nodeIdx int
-	for idx, storageServer := range s.nw.StorageServers {
-		if storageServer == req.GetNodeAddress() {
-			nodeIdx = idx
-			break
-		}
-	} 
-	// Remove node from hash ring
-	s.nw.StorageServers = slices.Delete(s.nw.StorageServers, nodeIdx, nodeIdx + 1)
-	s.nw.initHashRing()
+	s.nw.mu.RLock()
+	drainInProgress := s.nw.drainInProgress()
+	s.nw.mu.RUnlock()
+	if drainInProgress {
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot remove a node while a drain is in progress")
+	}
 
-	// Open connection to node
-	conn, err := grpc.NewClient(req.GetNodeAddress(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	_, files, err := s.nw.startDrain(ctx, req.GetNodeAddress())
 	if err != nil {
 		return nil, err
 	}
-	client := pb.NewNetworkVideoContentClient(conn)
-
-	// Find out which files need to be displaced
-	response, err := client.List(context.Background(), &pb.ListRequest{})
+	migrated, err := s.nw.drain(ctx, req.GetNodeAddress(), files)
 	if err != nil {
 		return nil, err
 	}
-	displacedFiles := response.GetFileIds()
 
-	// Move displaced files to new hash ring
-	for _, file := range displacedFiles {
-		// Read file
-		response, err := client.Read(context.Background(), &pb.ReadRequest{
-			FileId: file,
-		})
-		if err != nil {
-			return nil, err
+	return &pb.RemoveNodeResponse{MigratedFileCount: migrated}, nil
+}
+
+func (s *VideoContentAdminServer) ListNodes(ctx context.Context, req *pb.ListNodesRequest) (*pb.ListNodesResponse, error) {
+	s.nw.mu.RLock()
+	response := &pb.ListNodesResponse{Nodes: slices.Clone(s.nw.StorageServers)}
+	for _, storageServer := range s.nw.StorageServers {
+		info := s.nw.nodeInfo(storageServer)
+		info.RingPosition = hashStringToUint64(storageServer)
+		info.KeySpaceShare = keySpaceShare(s.nw.Nodes, storageServer)
+		info.Draining = s.nw.isDraining(storageServer)
+		response.NodeInfos = append(response.NodeInfos, info)
+	}
+	s.nw.mu.RUnlock()
+
+	// Ask every node for its usage in parallel
+	var wg sync.WaitGroup
+	for _, info := range response.NodeInfos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.nw.fillNodeStatus(ctx, info)
+		}()
+	}
+	wg.Wait()
+
+	return response, nil
+}
+
+func (s *VideoContentAdminServer) PlanTopologyChange(ctx context.Context, req *pb.PlanTopologyChangeRequest) (*pb.PlanTopologyChangeResponse, error) {
+	s.nw.mu.RLock()
+	storageServers := slices.Clone(s.nw.StorageServers)
+	s.nw.mu.RUnlock()
+
+	// Work out the storage servers after the change
+	newStorageServers := slices.Clone(storageServers)
+	switch req.GetOperation() {
+	case pb.TopologyOperation_ADD:
+		if slices.Contains(newStorageServers, req.GetNodeAddress()) {
+			return nil, status.Errorf(codes.AlreadyExists, "node %s is already in the cluster", req.GetNodeAddress())
 		}
+		newStorageServers = append(newStorageServers, req.GetNodeAddress())
+	case pb.TopologyOperation_REMOVE:
+		idx := slices.Index(newStorageServers, req.GetNodeAddress())
+		if idx == -1 {
+			return nil, status.Errorf(codes.NotFound, "node %s is not in the cluster", req.GetNodeAddress())
+		}
+		newStorageServers = slices.Delete(newStorageServers, idx, idx + 1)
+		if len(newStorageServers) == 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "cannot remove the last node in the cluster")
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown operation %v", req.GetOperation())
+	}
+	newNodes := buildHashRing(newStorageServers)
 
-		// Write file to NW
-		s.nw.Write(strings.Split(file, "/")[0], strings.Split(file, "/")[1], response.GetData())
+	loads := make(map[string]*pb.NodeLoad)
+	for _, node := range newNodes {
+		loads[node.id] = &pb.NodeLoad{NodeAddress: node.id}
+	}
+	transfers := make(map[[2]string]*pb.PlannedTransfer)
+	response := &pb.PlanTopologyChangeResponse{}
 
-		// Delete file from old node
-		_, err = client.Delete(context.Background(), &pb.DeleteRequest{FileId: file})
+	// Find where every file currently stored would live on the new ring
+	for _, storageServer := range storageServers {
+		files, err := s.nw.listFiles(ctx, storageServer, nil)
 		if err != nil {
 			return nil, err
 		}
+
+		for _, file := range files {
+			target := locateOnRing(newNodes, file.GetFileId())
+			loads[target].FileCount++
+			loads[target].ByteCount += file.GetSize()
+			if target == storageServer {
+				continue
+			}
+
+			key := [2]string{storageServer, target}
+			if transfers[key] == nil {
+				transfers[key] = &pb.PlannedTransfer{Source: storageServer, Destination: target}
+			}
+			transfers[key].FileCount++
+			transfers[key].ByteCount += file.GetSize()
+			response.TotalFileCount++
+			response.TotalByteCount += file.GetSize()
+		}
 	}
-	
-	return &pb.RemoveNodeResponse{MigratedFileCount: int32(len(displacedFiles))}, nil
-}
 
-func (s *VideoContentAdminServer) ListNodes(ctx context.Context, req *pb.ListNodesRequest) (*pb.ListNodesResponse, error) {
-	return &pb.ListNodesResponse{Nodes: s.nw.StorageServers}, nil
+	for _, node := range newNodes {
+		response.NodeLoads = append(response.NodeLoads, loads[node.id])
+	}
+	for _, transfer := range transfers {
+		response.Transfers = append(response.Transfers, transfer)
+	}
+	slices.SortFunc(response.Transfers, func(a, b *pb.PlannedTransfer) int {
+		return strings.Compare(a.Source + " " + a.Destination, b.Source + " " + b.Destination)
+	})
+
+	return response, nil
 }
 
+// listPageSize is how many files are asked for at a time when listing a node.
+const listPageSize = 1000
+
 type NetworkVideoContentService struct{
-	initialized bool
+	initOnce sync.Once
 	AdminServer string
 	StorageServers []string
 	Nodes []Node
 	Directory map[string][]string
+
+	// MigrationParallelism is the number of files a storage node sends at
+	// once while rebalancing. Values below one are treated as one.
+	MigrationParallelism int
+	// MigrationBytesPerSecond caps the rate at which a storage node sends
+	// files while rebalancing. Zero means unlimited.
+	MigrationBytesPerSecond int64
+
+	// HealthCheckInterval is how often every storage node is probed. Zero
+	// uses a default of five seconds.
+	HealthCheckInterval time.Duration
+	// HealthCheckFailureThreshold is the number of failed probes in a row
+	// after which a node is marked down. Zero uses a default of three.
+	HealthCheckFailureThreshold int
+
+	// ClientCredentials secure connections to storage nodes, and
+	// AdminCredentials those accepted by the admin server. Both default to
+	// plaintext.
+	ClientCredentials credentials.TransportCredentials
+	AdminCredentials credentials.TransportCredentials
+	// AdminAccess decides who may call the admin server, and AdminAuditLog
+	// records every call. The audit log defaults to the default slog logger.
+	AdminAccess AdminAccess
+	AdminAuditLog *slog.Logger
+
+	// mu guards StorageServers, Nodes, readNodes, drains and health, which the
+	// admin server and health checks change while requests are being served.
+	mu sync.RWMutex
+	// readNodes is the hash ring including draining nodes, which keep serving
+	// reads for their files until the drain completes. Nodes excludes them so
+	// that new writes go to the new owners.
+	readNodes []Node
+	drains map[string]*pb.DrainStatus
+	health map[string]*nodeHealth
+
+	// Controller is the address of a controller that owns the ring. If it is
+	// set, the ring is taken from the controller and kept up to date as it
+	// changes, StorageServers is ignored and no admin server is started.
+	// ControllerToken is sent to the controller as a bearer token.
+	Controller string
+	ControllerToken string
+
+	// ringVersion counts changes to the ring, and ringChanged is closed and
+	// replaced on every change to wake up ring watchers.
+	ringVersion uint64
+	ringChanged chan struct{}
+
+	// adminServer is set up by init, and done is closed by Shutdown to stop
+	// background work. initErr is why init failed, if it did.
+	adminServer *grpc.Server
+	done chan struct{}
+	initErr error
 }
 
-func (s *NetworkVideoContentService) initHashRing() {
-	s.Nodes = []Node{}
+// buildHashRing places the given storage servers on a hash ring.
+func buildHashRing(storageServers []string) []Node {
+	nodes := []Node{}
 
-	for _, nodeId := range s.StorageServers {
-		s.Nodes = append(s.Nodes, Node{
+	for _, nodeId := range storageServers {
+		nodes = append(nodes, Node{
 			hash: hashStringToUint64(nodeId),
 			id: nodeId,
 		})
 	}
 
-	sort.Slice(s.Nodes, func(i, j int) bool {
-		return s.Nodes[i].hash < s.Nodes[j].hash
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].hash < nodes[j].hash
 	})
+
+	return nodes
+}
+
+// locateOnRing finds the node on the hash ring responsible for a file.
+func locateOnRing(nodes []Node, fileId string) string {
+	fileHash := hashStringToUint64(fileId)
+
+	// Find the target node (smallest hash where video_hash < node_hash)
+	for _, node := range nodes {
+		if fileHash < node.hash {
+			return node.id
+		}
+	}
+
+	return nodes[0].id
+}
+
+// ringSuccessors lists every node on the hash ring, starting with the one
+// responsible for a file and going round the ring from there.
+func ringSuccessors(nodes []Node, fileId string) []string {
+	owner := locateOnRing(nodes, fileId)
+	idx := slices.IndexFunc(nodes, func(node Node) bool { return node.id == owner })
+
+	var successors []string
+	for i := range nodes {
+		successors = append(successors, nodes[(idx + i) % len(nodes)].id)
+	}
+	return successors
+}
+
+// ownedRange is the part of the ring a node owns, or nil if it is not on the
+// ring.
+func ownedRange(nodes []Node, nodeId string) *pb.HashRange {
+	idx := slices.IndexFunc(nodes, func(node Node) bool { return node.id == nodeId })
+	if idx == -1 {
+		return nil
+	}
+
+	previous := nodes[(idx + len(nodes) - 1) % len(nodes)]
+	return &pb.HashRange{Start: previous.hash, End: nodes[idx].hash}
+}
+
+// keySpaceShare is the fraction of the hash space a node owns on the ring,
+// or zero if it is not on the ring.
+func keySpaceShare(nodes []Node, nodeId string) float64 {
+	idx := slices.IndexFunc(nodes, func(node Node) bool { return node.id == nodeId })
+	if idx == -1 {
+		return 0
+	}
+	if len(nodes) == 1 {
+		return 1
+	}
+
+	// A node owns the keys from the previous node's hash up to its own,
+	// wrapping around the end of the ring
+	previous := nodes[(idx + len(nodes) - 1) % len(nodes)]
+	width := nodes[idx].hash - previous.hash
+	return float64(width) / math.Pow(2, 64)
+}
+
+func (s *NetworkVideoContentService) initHashRing() {
+	var writableServers []string
+	for _, storageServer := range s.StorageServers {
+		if !s.isDraining(storageServer) {
+			writableServers = append(writableServers, storageServer)
+		}
+	}
+
+	s.Nodes = buildHashRing(writableServers)
+	s.readNodes = buildHashRing(s.StorageServers)
+
+	ringNodes.WithLabelValues("writable").Set(float64(len(s.Nodes)))
+	ringNodes.WithLabelValues("draining").Set(float64(len(s.readNodes) - len(s.Nodes)))
+
+	s.ringVersion++
+	if s.ringChanged != nil {
+		close(s.ringChanged)
+	}
+	s.ringChanged = make(chan struct{})
 }
 
-func (s *NetworkVideoContentService) initAdminServer() {
+func (s *NetworkVideoContentService) initAdminServer() error {
 	lis, err := net.Listen("tcp", s.AdminServer)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		return fmt.Errorf("listening on %s: %w", s.AdminServer, err)
 	}
 
-	gs := grpc.NewServer()
+	creds := s.AdminCredentials
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	gs := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor, logging.UnaryServerInterceptor, s.adminUnaryInterceptor),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor, logging.StreamServerInterceptor, s.adminStreamInterceptor),
+		tracing.ServerOption(),
+	)
 	pb.RegisterVideoContentAdminServiceServer(gs, &VideoContentAdminServer{nw: s})
-	
+	s.adminServer = gs
+
 	go func() {
-		if err := gs.Serve(lis); err != nil {
-			log.Fatalf("Failed to serve: %v", err)
+		if err := gs.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			slog.Error("Admin server stopped", "err", err)
 		}
 	}()
+	return nil
 }
 
 func (s *NetworkVideoContentService) getNWLocation(videoId string, filename string) string {
-	videoHash := hashStringToUint64(videoId + "/" + filename)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	// Find the target node (smallest hash where video_hash < node_hash)
-	var targetNodeId string
-	for _, node := range s.Nodes {
-		if videoHash < node.hash {
-			targetNodeId = node.id
-			break
+	return locateOnRing(s.Nodes, videoId + "/" + filename)
+}
+
+// getNWWriteLocations lists the nodes a file may be written to, in the order
+// they should be tried: the file's owner, then the nodes after it on the
+// ring. Nodes known to be full are tried last.
+func (s *NetworkVideoContentService) getNWWriteLocations(videoId string, filename string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.Nodes) == 0 {
+		return nil
+	}
+
+	locations := ringSuccessors(s.Nodes, videoId + "/" + filename)
+	slices.SortStableFunc(locations, func(a, b string) int {
+		if s.isFull(a) == s.isFull(b) {
+			return 0
+		} else if s.isFull(a) {
+			return 1
+		}
+		return -1
+	})
+
+	return locations
+}
+
+// getNWReadLocations lists the nodes that may hold a file, in the order they
+// should be tried. A draining node is tried first for the files it owned, and
+// the file's new owner after it. The nodes after the owner on the ring come
+// last, as writes spill over to them while the owner is full. Nodes that are
+// down are tried after all of those.
+func (s *NetworkVideoContentService) getNWReadLocations(videoId string, filename string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var locations []string
+	if len(s.readNodes) > 0 {
+		previousOwner := locateOnRing(s.readNodes, videoId + "/" + filename)
+		if s.isDraining(previousOwner) {
+			locations = append(locations, previousOwner)
 		}
 	}
-	if targetNodeId == "" {
-		targetNodeId = s.Nodes[0].id
+
+	if len(s.Nodes) > 0 {
+		for _, nodeId := range ringSuccessors(s.Nodes, videoId + "/" + filename) {
+			if !slices.Contains(locations, nodeId) {
+				locations = append(locations, nodeId)
+			}
+		}
 	}
 
-	return targetNodeId
+	// Route around nodes that are down wherever another copy exists
+	slices.SortStableFunc(locations, func(a, b string) int {
+		if s.isDown(a) == s.isDown(b) {
+			return 0
+		} else if s.isDown(a) {
+			return 1
+		}
+		return -1
+	})
+
+	return locations
 }
 
-func (s *NetworkVideoContentService) openNWClient(videoId string, filename string) (pb.NetworkVideoContentClient, error) {
-	if !s.initialized {
-		s.initHashRing()
-		s.initAdminServer()
-		s.initialized = true
+// fillNodeStatus asks a node for its usage and records it in info, or records
+// why it could not.
+func (s *NetworkVideoContentService) fillNodeStatus(ctx context.Context, info *pb.NodeInfo) {
+	client, conn, err := s.openNWClient(info.NodeAddress)
+	if err != nil {
+		info.StatusError = err.Error()
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	response, err := client.Status(ctx, &pb.StatusRequest{})
+	if err != nil {
+		info.StatusError = err.Error()
+		return
 	}
-	
-	targetNodeId := s.getNWLocation(videoId, filename)
-	
-	conn, err := grpc.NewClient(targetNodeId, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	info.FileCount = response.GetFileCount()
+	info.BytesUsed = response.GetBytesUsed()
+	info.BytesFree = response.GetBytesFree()
+	info.UptimeSeconds = response.GetUptimeSeconds()
+	info.Version = response.GetVersion()
+	info.QuotaBytes = response.GetQuotaBytes()
+	info.Full = response.GetFull()
+}
+
+// listFiles lists every file stored on a node along with its size, one page
+// at a time. If hashRange is set, only files in that part of the ring are
+// listed.
+func (s *NetworkVideoContentService) listFiles(ctx context.Context, nodeId string, hashRange *pb.HashRange) ([]*pb.FileInfo, error) {
+	client, conn, err := s.openNWClient(nodeId)
 	if err != nil {
 		return nil, err
 	}
+	defer conn.Close()
+
+	var files []*pb.FileInfo
+	pageToken := ""
+	for {
+		response, err := client.List(ctx, &pb.ListRequest{
+			PageToken: pageToken,
+			PageSize: listPageSize,
+			HashRange: hashRange,
+		})
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, response.GetFiles()...)
+
+		pageToken = response.GetNextPageToken()
+		if pageToken == "" {
+			return files, nil
+		}
+	}
+}
+
+// transferFiles asks the source node to push the given files directly to the
+// destination node, which then owns them. The source keeps its copies if
+// keepSource is set. It returns the number of files and bytes transferred.
+func (s *NetworkVideoContentService) transferFiles(ctx context.Context, source string, destination string, fileIds []string, keepSource bool) (int32, int64, error) {
+	if len(fileIds) == 0 {
+		return 0, 0, nil
+	}
+
+	client, conn, err := s.openNWClient(source)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	started := time.Now()
+	response, err := client.Transfer(ctx, &pb.TransferRequest{
+		FileIds: fileIds,
+		Destination: destination,
+		Parallelism: int32(s.MigrationParallelism),
+		BytesPerSecond: s.MigrationBytesPerSecond,
+		KeepSource: keepSource,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	migratedFiles.Add(float64(response.GetTransferredFileCount()))
+	migratedBytes.Add(float64(response.GetTransferredBytes()))
+	slog.InfoContext(ctx, "Transferred files", "node", source, "destination", destination, "files", response.GetTransferredFileCount(), "bytes", response.GetTransferredBytes(), "duration", time.Since(started))
+	return response.GetTransferredFileCount(), response.GetTransferredBytes(), nil
+}
+
+// init brings the service up the first time it is needed, returning the
+// error it failed with, if any, on every call.
+func (s *NetworkVideoContentService) init() error {
+	s.initOnce.Do(func() {
+		s.done = make(chan struct{})
+		s.initHashRing()
+		if s.Controller != "" {
+			go s.watchRing()
+		} else {
+			s.initErr = s.initAdminServer()
+			if s.initErr != nil {
+				return
+			}
+		}
+		s.startHealthChecks()
+	})
+	return s.initErr
+}
+
+// Start brings the service up without waiting for the first request, so that
+// the admin server is reachable straight away.
+func (s *NetworkVideoContentService) Start() error {
+	return s.init()
+}
+
+// Shutdown stops the health checks, ring watchers and the admin server,
+// letting admin requests in flight finish until ctx is done. Drains still
+// running are abandoned, and can be started again once the service is back
+// up.
+func (s *NetworkVideoContentService) Shutdown(ctx context.Context) error {
+	// Wait for init if it is running, and stop it from running later
+	s.initOnce.Do(func() {})
+	if s.done == nil {
+		return nil
+	}
+	close(s.done)
+
+	if s.adminServer == nil {
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.adminServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.adminServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// dialNode connects to a storage node. The caller must close the returned
+// connection.
+func (s *NetworkVideoContentService) dialNode(nodeId string) (*grpc.ClientConn, error) {
+	creds := s.ClientCredentials
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	return grpc.NewClient(nodeId,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor, logging.UnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(logging.StreamClientInterceptor),
+		tracing.DialOption(),
+	)
+}
+
+// openNWClient connects to a storage node. The caller must close the returned
+// connection.
+func (s *NetworkVideoContentService) openNWClient(nodeId string) (pb.NetworkVideoContentClient, *grpc.ClientConn, error) {
+	conn, err := s.dialNode(nodeId)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	client := pb.NewNetworkVideoContentClient(conn)
 
-	return client, nil
+	return client, conn, nil
+}
+
+func (s *NetworkVideoContentService) Read(ctx context.Context, videoId string, filename string) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "NetworkVideoContentService.Read", attribute.String("video_id", videoId), attribute.String("file", filename))
+	data, err := s.read(ctx, videoId, filename)
+	tracing.End(span, err)
+	return data, err
 }
 
-func (s *NetworkVideoContentService) Read(videoId string, filename string) ([]byte, error) {
-	client, err := s.openNWClient(videoId, filename)
+func (s *NetworkVideoContentService) read(ctx context.Context, videoId string, filename string) ([]byte, error) {
+	err := fileid.Validate(videoId, filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	err = s.init()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	// Only report the file as missing if no node failed to answer, as the
+	// file may be on the one that did
+	locations := s.getNWReadLocations(videoId, filename)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.StringSlice("nodes", locations))
+
+	var lastErr error
+	for _, nodeId := range locations {
+		data, err := s.readFromNode(ctx, nodeId, videoId, filename)
+		if err == nil {
+			return data, nil
+		}
+		if status.Code(err) == codes.Unavailable {
+			s.recordHealth(nodeId, err)
+		}
+		if status.Code(err) != codes.NotFound || lastErr == nil {
+			lastErr = err
+		}
+	}
+	if lastErr == nil {
+		return nil, fmt.Errorf("%w: no storage nodes", ErrUnavailable)
+	}
+
+	return nil, fromStatus(lastErr)
+}
+
+func (s *NetworkVideoContentService) readFromNode(ctx context.Context, nodeId string, videoId string, filename string) ([]byte, error) {
+	client, conn, err := s.openNWClient(nodeId)
 	if err != nil {
 		return nil, err
 	}
+	defer conn.Close()
 
-	response, err := client.Read(context.Background(), &pb.ReadRequest{
-		FileId: videoId + "/" + filename,
+	started := time.Now()
+	response, err := client.Read(ctx, &pb.ReadRequest{
+		FileId: fileid.Join(videoId, filename),
 	})
+	slog.DebugContext(ctx, "Read file from storage node", "video_id", videoId, "file", filename, "node", nodeId, "duration", time.Since(started), "err", err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the data was not corrupted on disk or on the way here
+	checksum := sha256.Sum256(response.GetData())
+	if len(response.GetSha256()) > 0 && !bytes.Equal(response.GetSha256(), checksum[:]) {
+		slog.WarnContext(ctx, "Checksum mismatch", "video_id", videoId, "file", filename, "node", nodeId)
+		return nil, fmt.Errorf("checksum mismatch for %s/%s from %s", videoId, filename, nodeId)
+	}
+
+	return response.GetData(), nil
+}
+
+func (s *NetworkVideoContentService) Write(ctx context.Context, videoId string, filename string, data []byte) error {
+	ctx, span := tracing.Start(ctx, "NetworkVideoContentService.Write", attribute.String("video_id", videoId), attribute.String("file", filename), attribute.Int("bytes", len(data)))
+	err := s.write(ctx, videoId, filename, data)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *NetworkVideoContentService) write(ctx context.Context, videoId string, filename string, data []byte) error {
+	err := fileid.Validate(videoId, filename)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	err = s.init()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	// Spill over to the next node on the ring while the owner is full
+	locations := s.getNWWriteLocations(videoId, filename)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.StringSlice("nodes", locations))
+	if len(locations) == 0 {
+		return fmt.Errorf("%w: no storage nodes", ErrUnavailable)
+	}
+
+	checksum := sha256.Sum256(data)
+	for _, nodeId := range locations {
+		err := s.writeToNode(ctx, nodeId, videoId, filename, data, checksum[:])
+		if status.Code(err) == codes.ResourceExhausted {
+			s.recordFull(nodeId, true)
+			continue
+		} else if status.Code(err) == codes.Unavailable {
+			// Only a full node is passed over. A copy spilled past a node
+			// that is merely down would be hidden behind its older one once
+			// it is back, as reads try the owner first.
+			s.recordHealth(nodeId, err)
+			return fromStatus(err)
+		} else if err != nil {
+			return fromStatus(err)
+		}
+		return nil
+	}
 
-	return response.GetData(), err
+	return fmt.Errorf("%w: every storage node is full", ErrStorageFull)
 }
 
-func (s *NetworkVideoContentService) Write(videoId string, filename string, data []byte) error {
-	client, err := s.openNWClient(videoId, filename)
+func (s *NetworkVideoContentService) writeToNode(ctx context.Context, nodeId string, videoId string, filename string, data []byte, checksum []byte) error {
+	client, conn, err := s.openNWClient(nodeId)
 	if err != nil {
 		return err
 	}
+	defer conn.Close()
 
-	_, err = client.Write(context.Background(), &pb.WriteRequest{
-		FileId: videoId + "/" + filename,
+	started := time.Now()
+	_, err = client.Write(ctx, &pb.WriteRequest{
+		FileId: fileid.Join(videoId, filename),
 		Data: data,
+		Sha256: checksum,
 	})
+	slog.DebugContext(ctx, "Wrote file to storage node", "video_id", videoId, "file", filename, "node", nodeId, "duration", time.Since(started), "err", err)
+	return err
+}
+
+// Delete removes every file of a video. A video's files are spread over the
+// whole ring, so every node is asked to delete its share.
+func (s *NetworkVideoContentService) Delete(ctx context.Context, videoId string) error {
+	ctx, span := tracing.Start(ctx, "NetworkVideoContentService.Delete", attribute.String("video_id", videoId))
+	err := s.delete(ctx, videoId)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *NetworkVideoContentService) delete(ctx context.Context, videoId string) error {
+	err := fileid.ValidateName(videoId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	err = s.init()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	s.mu.RLock()
+	storageServers := slices.Clone(s.StorageServers)
+	s.mu.RUnlock()
+
+	var found bool
+	for _, nodeId := range storageServers {
+		err := s.deleteVideo(ctx, nodeId, videoId)
+		if status.Code(err) == codes.NotFound {
+			continue
+		} else if err != nil {
+			return fromStatus(err)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrNotFound, videoId)
+	}
+
+	return nil
+}
+
+func (s *NetworkVideoContentService) deleteVideo(ctx context.Context, nodeId string, videoId string) error {
+	client, conn, err := s.openNWClient(nodeId)
 	if err != nil {
 		return err
 	}
+	defer conn.Close()
 
+	_, err = client.DeleteVideo(ctx, &pb.DeleteVideoRequest{VideoId: videoId})
 	return err
 }
 
+// deleteFiles removes the given files from a node that no longer owns them.
+// Only the listed files are deleted, so a write that reached the node after
+// they were listed is left alone.
+func (s *NetworkVideoContentService) deleteFiles(ctx context.Context, nodeId string, files []*pb.FileInfo) error {
+	client, conn, err := s.openNWClient(nodeId)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, file := range files {
+		_, err := client.Delete(ctx, &pb.DeleteRequest{FileId: file.GetFileId()})
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Uncomment the following line to ensure NetworkVideoContentService implements VideoContentService
 var _ VideoContentService = (*NetworkVideoContentService)(nil)
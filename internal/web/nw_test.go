@@ -0,0 +1,242 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"tritontube/internal/hashring"
+	pb "tritontube/internal/proto"
+	"tritontube/internal/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// startStorageNode serves a storage node on a free local port, returning its
+// address.
+func startStorageNode(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	pb.RegisterNetworkVideoContentServer(gs, &storage.NetworkVideoContentServer{Dir: t.TempDir(), TransferPeers: []string{"*"}})
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+	return lis.Addr().String()
+}
+
+// startService starts a network content service over the given storage
+// nodes, returning its admin server.
+func startService(t *testing.T, storageServers ...string) *VideoContentAdminServer {
+	t.Helper()
+	nw := &NetworkVideoContentService{AdminServer: "127.0.0.1:0", StorageServers: storageServers}
+	err := nw.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nw.Shutdown(context.Background()) })
+	return &VideoContentAdminServer{nw: nw}
+}
+
+func TestAddNodeMovesSpilledFiles(t *testing.T) {
+	ctx := context.Background()
+	first, second, added := startStorageNode(t), startStorageNode(t), startStorageNode(t)
+	admin := startService(t, first, second)
+	nw := admin.nw
+
+	// Find a file the added node will own, and put it on the node that does
+	// not take over the added node's range, as if its owner had been full
+	ring := buildHashRing([]string{first, second, added})
+	hashRange := ownedRange(ring, added)
+	var videoId string
+	for i := 0; videoId == ""; i++ {
+		candidate := fmt.Sprintf("video%d", i)
+		if hashring.InRange(hashring.Hash(candidate+"/manifest.mpd"), hashRange.GetStart(), hashRange.GetEnd()) {
+			videoId = candidate
+		}
+	}
+	spilledTo := ringSuccessors(buildHashRing([]string{first, second}), videoId+"/manifest.mpd")[1]
+	err := nw.writeToNode(ctx, spilledTo, videoId, "manifest.mpd", []byte("manifest"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := admin.AddNode(ctx, &pb.AddNodeRequest{NodeAddress: added})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.GetMigratedFileCount() != 1 {
+		t.Errorf("AddNode migrated %d files, want 1", response.GetMigratedFileCount())
+	}
+
+	data, err := nw.readFromNode(ctx, added, videoId, "manifest.mpd")
+	if err != nil || string(data) != "manifest" {
+		t.Errorf("reading from the added node = %q, %v, want \"manifest\"", data, err)
+	}
+	files, err := nw.listFiles(ctx, spilledTo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("%s still holds %d files", spilledTo, len(files))
+	}
+}
+
+func TestStartReportsAdminServerErrors(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	// The admin address is taken, so the service cannot come up
+	nw := &NetworkVideoContentService{AdminServer: lis.Addr().String(), StorageServers: []string{startStorageNode(t)}}
+	err = nw.Start()
+	if err == nil {
+		t.Fatal("Start succeeded with the admin address in use")
+	}
+	_, err = nw.Read(context.Background(), "video", "manifest.mpd")
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Read after a failed start = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestAddNodeRefusesExistingNode(t *testing.T) {
+	ctx := context.Background()
+	node := startStorageNode(t)
+	admin := startService(t, node)
+
+	_, err := admin.AddNode(ctx, &pb.AddNodeRequest{NodeAddress: node})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("AddNode of a node in the cluster = %v, want AlreadyExists", err)
+	}
+	if len(admin.nw.StorageServers) != 1 || len(admin.nw.Nodes) != 1 {
+		t.Errorf("the cluster has %v after adding an existing node", admin.nw.StorageServers)
+	}
+}
+
+func TestPlanTopologyChange(t *testing.T) {
+	ctx := context.Background()
+	first, second := startStorageNode(t), startStorageNode(t)
+	admin := startService(t, first, second)
+	added := "127.0.0.1:1"
+
+	// Store some files where the current ring puts them
+	holders := make(map[string]string)
+	sizes := make(map[string]int64)
+	for i := range 8 {
+		videoId := fmt.Sprintf("video%d", i)
+		data := []byte(strings.Repeat("x", i+1))
+		nodeId := locateOnRing(admin.nw.Nodes, videoId+"/manifest.mpd")
+		err := admin.nw.writeToNode(ctx, nodeId, videoId, "manifest.mpd", data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		holders[videoId+"/manifest.mpd"] = nodeId
+		sizes[videoId+"/manifest.mpd"] = int64(len(data))
+	}
+
+	tests := []struct {
+		operation pb.TopologyOperation
+		node      string
+		after     []string
+	}{
+		{pb.TopologyOperation_ADD, added, []string{first, second, added}},
+		{pb.TopologyOperation_REMOVE, first, []string{second}},
+	}
+	for _, test := range tests {
+		response, err := admin.PlanTopologyChange(ctx, &pb.PlanTopologyChangeRequest{Operation: test.operation, NodeAddress: test.node})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Work out the plan file by file
+		ring := buildHashRing(test.after)
+		wantTransfers := make(map[string]*pb.PlannedTransfer)
+		wantLoads := make(map[string]*pb.NodeLoad)
+		for _, node := range ring {
+			wantLoads[node.id] = &pb.NodeLoad{NodeAddress: node.id}
+		}
+		var wantFiles int32
+		var wantBytes int64
+		for fileId, source := range holders {
+			target := locateOnRing(ring, fileId)
+			wantLoads[target].FileCount++
+			wantLoads[target].ByteCount += sizes[fileId]
+			if target == source {
+				continue
+			}
+			key := source + " " + target
+			if wantTransfers[key] == nil {
+				wantTransfers[key] = &pb.PlannedTransfer{Source: source, Destination: target}
+			}
+			wantTransfers[key].FileCount++
+			wantTransfers[key].ByteCount += sizes[fileId]
+			wantFiles++
+			wantBytes += sizes[fileId]
+		}
+
+		if response.GetTotalFileCount() != wantFiles || response.GetTotalByteCount() != wantBytes {
+			t.Errorf("%v %s moves %d files and %d bytes, want %d and %d", test.operation, test.node,
+				response.GetTotalFileCount(), response.GetTotalByteCount(), wantFiles, wantBytes)
+		}
+		if len(response.GetTransfers()) != len(wantTransfers) {
+			t.Errorf("%v %s plans %d transfers, want %d", test.operation, test.node, len(response.GetTransfers()), len(wantTransfers))
+		}
+		for _, transfer := range response.GetTransfers() {
+			want := wantTransfers[transfer.GetSource()+" "+transfer.GetDestination()]
+			if !proto.Equal(transfer, want) {
+				t.Errorf("%v %s plans transfer %v, want %v", test.operation, test.node, transfer, want)
+			}
+		}
+		if len(response.GetNodeLoads()) != len(ring) {
+			t.Errorf("%v %s reports %d node loads, want %d", test.operation, test.node, len(response.GetNodeLoads()), len(ring))
+		}
+		for _, load := range response.GetNodeLoads() {
+			if want := wantLoads[load.GetNodeAddress()]; !proto.Equal(load, want) {
+				t.Errorf("%v %s reports load %v, want %v", test.operation, test.node, load, want)
+			}
+		}
+	}
+
+	// Planning changes nothing
+	if len(admin.nw.StorageServers) != 2 {
+		t.Errorf("the cluster has %v after planning", admin.nw.StorageServers)
+	}
+	for fileId, nodeId := range holders {
+		videoId, filename, _ := strings.Cut(fileId, "/")
+		if _, err := admin.nw.readFromNode(ctx, nodeId, videoId, filename); err != nil {
+			t.Errorf("reading %s from %s after planning: %v", fileId, nodeId, err)
+		}
+	}
+}
+
+func TestPlanTopologyChangeErrors(t *testing.T) {
+	ctx := context.Background()
+	node := startStorageNode(t)
+	admin := startService(t, node)
+
+	tests := []struct {
+		operation pb.TopologyOperation
+		node      string
+		want      codes.Code
+	}{
+		{pb.TopologyOperation_ADD, node, codes.AlreadyExists},
+		{pb.TopologyOperation_REMOVE, "127.0.0.1:1", codes.NotFound},
+		{pb.TopologyOperation_REMOVE, node, codes.FailedPrecondition},
+		{pb.TopologyOperation(99), node, codes.InvalidArgument},
+	}
+	for _, test := range tests {
+		_, err := admin.PlanTopologyChange(ctx, &pb.PlanTopologyChangeRequest{Operation: test.operation, NodeAddress: test.node})
+		if status.Code(err) != test.want {
+			t.Errorf("planning %v %s = %v, want %v", test.operation, test.node, err, test.want)
+		}
+	}
+}
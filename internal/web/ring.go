@@ -0,0 +1,120 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	pb "tritontube/internal/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ringRetryInterval is how long to wait before reconnecting to the controller
+// after losing the ring stream.
+const ringRetryInterval = 2 * time.Second
+
+// ringSnapshot describes the current ring. The caller must hold s.mu.
+func (s *NetworkVideoContentService) ringSnapshot() *pb.RingSnapshot {
+	snapshot := &pb.RingSnapshot{
+		Version:        s.ringVersion,
+		StorageServers: slices.Clone(s.StorageServers),
+	}
+	for _, storageServer := range s.StorageServers {
+		if s.isDraining(storageServer) {
+			snapshot.DrainingNodes = append(snapshot.DrainingNodes, storageServer)
+		}
+	}
+	return snapshot
+}
+
+func (s *VideoContentAdminServer) WatchRing(req *pb.WatchRingRequest, stream grpc.ServerStreamingServer[pb.RingSnapshot]) error {
+	for {
+		s.nw.mu.RLock()
+		snapshot := s.nw.ringSnapshot()
+		changed := s.nw.ringChanged
+		s.nw.mu.RUnlock()
+
+		err := stream.Send(snapshot)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-s.nw.done:
+			return status.Error(codes.Unavailable, "shutting down")
+		}
+	}
+}
+
+// watchRing follows the controller's ring until the service is shut down,
+// reconnecting whenever the stream breaks.
+func (s *NetworkVideoContentService) watchRing() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.done
+		cancel()
+	}()
+
+	for {
+		err := s.followRing(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("Error while watching ring", "node", s.Controller, "err", err)
+
+		select {
+		case <-time.After(ringRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// followRing applies every ring snapshot the controller sends until the
+// stream breaks.
+func (s *NetworkVideoContentService) followRing(ctx context.Context) error {
+	conn, err := s.dialNode(s.Controller)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if s.ControllerToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+s.ControllerToken)
+	}
+	stream, err := pb.NewVideoContentAdminServiceClient(conn).WatchRing(ctx, &pb.WatchRingRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		snapshot, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		s.applyRingSnapshot(snapshot)
+	}
+}
+
+// applyRingSnapshot replaces the ring with the one from the controller.
+func (s *NetworkVideoContentService) applyRingSnapshot(snapshot *pb.RingSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.StorageServers = snapshot.GetStorageServers()
+	s.drains = make(map[string]*pb.DrainStatus)
+	for _, nodeId := range snapshot.GetDrainingNodes() {
+		s.drains[nodeId] = &pb.DrainStatus{NodeAddress: nodeId, State: pb.DrainState_DRAINING}
+	}
+	s.initHashRing()
+
+	slog.Info("Using ring from controller", "node", s.Controller, "version", snapshot.GetVersion(), "nodes", len(snapshot.GetStorageServers()), "draining", len(snapshot.GetDrainingNodes()))
+}
@@ -3,11 +3,13 @@
 package web
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
@@ -18,6 +20,7 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	"tritontube/internal/fileid"
 )
 
 type server struct {
@@ -27,7 +30,25 @@ type server struct {
 	metadataService VideoMetadataService
 	contentService  VideoContentService
 
+	// MaxUploadBytes caps the size of an upload. Zero means no limit.
+	MaxUploadBytes int64
+	// TranscodeProfiles are the qualities uploads are converted to, and
+	// AudioBitrate the bitrate of their audio. They default to
+	// DefaultTranscodeProfiles and DefaultAudioBitrate.
+	TranscodeProfiles []TranscodeProfile
+	AudioBitrate string
+	// AdminAccess decides who may delete videos, which takes the operator
+	// role.
+	AdminAccess AdminAccess
+
 	mux *http.ServeMux
+	httpServer *http.Server
+}
+
+// shutdowner is implemented by services that run servers or background work
+// of their own.
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
 }
 
 func NewServer(
@@ -35,26 +56,56 @@ func NewServer(
 	contentService VideoContentService,
 ) *server {
 	return &server{
-		metadataService: metadataService,
+		metadataService: tracedMetadataService{metadataService},
 		contentService:  contentService,
+		httpServer:      &http.Server{},
 	}
 }
 
 func (s *server) Start(lis net.Listener) error {
 	s.mux = http.NewServeMux()
-	s.mux.HandleFunc("/upload", s.handleUpload)
-	s.mux.HandleFunc("/videos/", s.handleVideo)
-	s.mux.HandleFunc("/content/", s.handleVideoContent)
-	s.mux.HandleFunc("/", s.handleIndex)
+	s.mux.Handle("/upload", instrument("/upload", http.HandlerFunc(s.handleUpload)))
+	s.mux.Handle("/videos/", instrument("/videos/", http.HandlerFunc(s.handleVideo)))
+	s.mux.Handle("/content/", instrument("/content/", http.HandlerFunc(s.handleVideoContent)))
+	s.mux.Handle("/", instrument("/", http.HandlerFunc(s.handleIndex)))
 
-	return http.Serve(lis, s.mux)
+	s.httpServer.Handler = s.mux
+	return s.httpServer.Serve(lis)
+}
+
+// Shutdown stops accepting connections and waits until ctx is done for
+// requests in flight, including uploads being transcoded, to finish. Any still
+// running then are cut off. The services are shut down once requests stop.
+func (s *server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		s.httpServer.Close()
+	}
+
+	for _, service := range []any{s.contentService, s.metadataService} {
+		if service, ok := service.(shutdowner); ok {
+			err = errors.Join(err, service.Shutdown(ctx))
+		}
+	}
+
+	return err
+}
+
+// lastLines returns up to the last n lines of output, for logging what a
+// command printed before it failed.
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
 }
 
 func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	metadata, err := s.metadataService.List()
+	metadata, err := s.metadataService.List(r.Context())
 	if err != nil {
 		msg := fmt.Sprintf("Error while fetching metadata: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while fetching metadata", "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
@@ -76,7 +127,7 @@ func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	tmpl, err := template.New("index").Parse(indexHTML)
 	if err != nil {
 		msg := fmt.Sprintf("Error while parsing template: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while parsing template", "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
@@ -84,29 +135,41 @@ func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	started := time.Now()
+	if s.MaxUploadBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.MaxUploadBytes)
+	}
 	err := r.ParseForm()
 	if err != nil {
 		msg := fmt.Sprintf("Error while parsing form: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while parsing form", "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
 
 	upload_file, upload_header, err := r.FormFile("file")
-	if err != nil {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Upload is larger than the limit of %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error while loading file from form: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while loading file from form", "err", err)
 		http.Error(w, msg, http.StatusBadRequest)
 		return
 	}
 	defer upload_file.Close()
 
 	videoId := strings.Split(path.Base(upload_header.Filename), ".")[0]
+	if fileid.ValidateName(videoId) != nil {
+		http.Error(w, "Invalid video id!", http.StatusBadRequest)
+		return
+	}
 
-	metadata, err := s.metadataService.Read(videoId)
+	metadata, err := s.metadataService.Read(r.Context(), videoId)
 	if err != nil {
 		msg := fmt.Sprintf("Error while reading metadata: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while reading metadata", "video_id", videoId, "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
@@ -118,7 +181,7 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	temp_file, err := os.CreateTemp("", upload_header.Filename)
 	if err != nil {
 		msg := fmt.Sprintf("Error while creating temp file: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while creating temp file", "video_id", videoId, "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
@@ -127,7 +190,7 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(upload_file)
 	if err != nil {
 		msg := fmt.Sprintf("Error while reading file data: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while reading file data", "video_id", videoId, "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
@@ -136,37 +199,46 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	tempDir, err := os.MkdirTemp("", upload_header.Filename)
 	if err != nil {
 		msg := fmt.Sprintf("Error while creating temp directory: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while creating temp directory", "video_id", videoId, "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
 	
 	manifestPath := filepath.Join(tempDir, "manifest.mpd")
 
-	cmd := exec.Command("ffmpeg",
-			"-i", temp_file.Name(), // input file
-			"-c:v", "libx264", // video codec
-			"-c:a", "aac", // audio codec
-			"-bf", "1", // max 1 b-frame
-			"-keyint_min", "120", // minimum keyframe interval
-			"-g", "120", // keyframe every 120 frames
-			"-sc_threshold", "0", // scene change threshold
-			"-b:v", "3000k", // video bitrate
-			"-b:a", "128k", // audio bitrate
-			"-f", "dash", // dash format
-			"-use_timeline", "1", // use timeline
-			"-use_template", "1", // use template
-			"-init_seg_name", "init-$RepresentationID$.m4s", // init segment naming
-			"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s", // media segment naming
-			"-seg_duration", "4", // segment duration in seconds
-			manifestPath) // output file
-	cmd.Run()
+	profiles := s.TranscodeProfiles
+	if len(profiles) == 0 {
+		profiles = DefaultTranscodeProfiles
+	}
+	audioBitrate := s.AudioBitrate
+	if audioBitrate == "" {
+		audioBitrate = DefaultAudioBitrate
+	}
+	cmd := exec.CommandContext(r.Context(), "ffmpeg", ffmpegArgs(temp_file.Name(), manifestPath, profiles, audioBitrate)...)
+	// Keep ffmpeg out of the terminal's process group, so that Ctrl-C leaves
+	// it to finish while the server shuts down
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	transcodeStarted := time.Now()
+	err = cmd.Run()
+	transcodeDuration.Observe(time.Since(transcodeStarted).Seconds())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error while transcoding", "video_id", videoId, "duration", time.Since(transcodeStarted), "err", err, "output", lastLines(output.String(), 5))
+	} else {
+		slog.InfoContext(r.Context(), "Transcoded video", "video_id", videoId, "duration", time.Since(transcodeStarted))
+	}
+
+	// Images are nice to have, so the upload goes ahead without them
+	makeImage(r.Context(), videoId, posterArgs(temp_file.Name(), filepath.Join(tempDir, posterFile)))
+	makeImage(r.Context(), videoId, previewArgs(temp_file.Name(), filepath.Join(tempDir, previewFile)))
 
 	
 	mpegDashFiles, err := os.ReadDir(tempDir)
 	if err != nil {
 		msg := fmt.Sprintf("Error while reading temp directory: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while reading temp directory", "video_id", videoId, "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
@@ -174,37 +246,60 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		data, err := os.ReadFile(path.Join(tempDir, file.Name()))
 		if err != nil {
 			msg := fmt.Sprintf("Error while reading temp file: %v", err)
-			log.Println(msg)
+			slog.ErrorContext(r.Context(), "Error while reading temp file", "video_id", videoId, "file", file.Name(), "err", err)
 			http.Error(w, msg, http.StatusInternalServerError)
 			return
 		}
-		err = s.contentService.Write(videoId, file.Name(), data)
+		err = s.contentService.Write(r.Context(), videoId, file.Name(), data)
 		if err != nil {
 			msg := fmt.Sprintf("Error while writing file to content service: %v", err)
-			log.Println(msg)
-			http.Error(w, msg, http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Error while writing file to content service", "video_id", videoId, "file", file.Name(), "err", err)
+			http.Error(w, msg, httpStatus(err))
 			return
 		}
 	}
 	
-	err = s.metadataService.Create(videoId, time.Now())
+	err = s.metadataService.Create(r.Context(), videoId, time.Now())
 	if err != nil {
 		msg := fmt.Sprintf("Error while creating metadata entry: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while creating metadata entry", "video_id", videoId, "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
 
+	uploadDuration.Observe(time.Since(started).Seconds())
+	slog.InfoContext(r.Context(), "Uploaded video", "video_id", videoId, "files", len(mpegDashFiles), "duration", time.Since(started))
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// makeImage runs ffmpeg with args to make an image from an upload, the last
+// argument being the image's path. If ffmpeg fails, the failure is logged and
+// anything it left behind is removed.
+func makeImage(ctx context.Context, videoId string, args []string) {
+	output := args[len(args)-1]
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	started := time.Now()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		slog.WarnContext(ctx, "Error while making image", "video_id", videoId, "file", filepath.Base(output), "duration", time.Since(started), "err", err, "output", lastLines(string(out), 5))
+		os.Remove(output)
+		return
+	}
+	slog.DebugContext(ctx, "Made image", "video_id", videoId, "file", filepath.Base(output), "duration", time.Since(started))
+}
+
 func (s *server) handleVideo(w http.ResponseWriter, r *http.Request) {
 	videoId := r.URL.Path[len("/videos/"):]
+	if r.Method == http.MethodDelete {
+		s.handleDeleteVideo(w, r, videoId)
+		return
+	}
 
-	metadata, err := s.metadataService.Read(videoId)
+	metadata, err := s.metadataService.Read(r.Context(), videoId)
 	if err != nil {
 		msg := fmt.Sprintf("Error while reading metadata: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while reading metadata", "video_id", videoId, "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
@@ -216,10 +311,60 @@ func (s *server) handleVideo(w http.ResponseWriter, r *http.Request) {
 	type VideoTmplData struct {
 		Id string
 		UploadedAt string
+		EscapedId string
 	}
 
 	tmpl := template.Must(template.New("index").Parse(videoHTML))
-	tmpl.Execute(w, VideoTmplData{videoId, metadata.UploadedAt.Format("2006-01-02 15:04:05")})
+	tmpl.Execute(w, VideoTmplData{videoId, metadata.UploadedAt.Format("2006-01-02 15:04:05"), url.PathEscape(videoId)})
+}
+
+// handleDeleteVideo removes a video's metadata and then its content, so
+// that it leaves the index before any of its files go. Deleting a video whose
+// content is already gone removes what is left of it.
+func (s *server) handleDeleteVideo(w http.ResponseWriter, r *http.Request, videoId string) {
+	caller, role := s.AdminAccess.authenticateHTTP(r)
+	if role == RoleNone {
+		http.Error(w, "A valid token is required", http.StatusUnauthorized)
+		return
+	} else if role < RoleOperator {
+		http.Error(w, "Deleting videos needs the operator role", http.StatusForbidden)
+		return
+	}
+	if fileid.ValidateName(videoId) != nil {
+		http.Error(w, "Invalid video id!", http.StatusBadRequest)
+		return
+	}
+
+	metadata, err := s.metadataService.Read(r.Context(), videoId)
+	if err != nil {
+		msg := fmt.Sprintf("Error while reading metadata: %v", err)
+		slog.ErrorContext(r.Context(), "Error while reading metadata", "video_id", videoId, "err", err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	if metadata != nil {
+		err = s.metadataService.Delete(r.Context(), videoId)
+		if err != nil {
+			msg := fmt.Sprintf("Error while deleting metadata: %v", err)
+			slog.ErrorContext(r.Context(), "Error while deleting metadata", "video_id", videoId, "err", err)
+			http.Error(w, msg, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = s.contentService.Delete(r.Context(), videoId)
+	if errors.Is(err, ErrNotFound) && metadata == nil {
+		http.Error(w, "No such videoId!", http.StatusNotFound)
+		return
+	} else if err != nil && !errors.Is(err, ErrNotFound) {
+		msg := fmt.Sprintf("Error while deleting content: %v", err)
+		slog.ErrorContext(r.Context(), "Error while deleting content", "video_id", videoId, "err", err)
+		http.Error(w, msg, httpStatus(err))
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Deleted video", "video_id", videoId, "caller", caller)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (s *server) handleVideoContent(w http.ResponseWriter, r *http.Request) {
@@ -232,21 +377,29 @@ func (s *server) handleVideoContent(w http.ResponseWriter, r *http.Request) {
 	}
 	videoId = parts[0]
 	filename := parts[1]
-
-	file, err := s.contentService.Read(videoId, filename)
-	if err != nil {
-		msg := fmt.Sprintf("Error while reading file from content service: %v", err)
-		log.Println(msg)
-		http.Error(w, msg, http.StatusInternalServerError)
+	if fileid.Validate(videoId, filename) != nil {
+		http.Error(w, "Invalid content path", http.StatusBadRequest)
 		return
 	}
-	if file == nil {
+
+	file, err := s.contentService.Read(r.Context(), videoId, filename)
+	if errors.Is(err, ErrNotFound) {
 		http.Error(w, "Content not found!", http.StatusNotFound)
 		return
+	} else if err != nil {
+		msg := fmt.Sprintf("Error while reading file from content service: %v", err)
+		slog.ErrorContext(r.Context(), "Error while reading file from content service", "video_id", videoId, "file", filename, "err", err)
+		http.Error(w, msg, httpStatus(err))
+		return
 	}
 	
-	w.Header().Set("Content-Type", "application/octet-stream")
-	_, err = w.Write(file)
+	contentType, ok := imageContentTypes[filename]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	n, err := w.Write(file)
+	contentBytesServed.Add(float64(n))
 	if err != nil {
 		// Ignore errors of the type "write tcp 127.0.0.1:8080->127.0.0.1:36822: write: broken pipe"
 		// Caused by the client closing the connection before the server finishes writing
@@ -254,7 +407,7 @@ func (s *server) handleVideoContent(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		msg := fmt.Sprintf("Error while sending data: %v", err)
-		log.Println(msg)
+		slog.ErrorContext(r.Context(), "Error while sending data", "video_id", videoId, "file", filename, "err", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
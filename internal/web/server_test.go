@@ -0,0 +1,215 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func FuzzContentPath(f *testing.F) {
+	for _, seed := range escapeSeeds {
+		f.Add(seed[0] + "/" + seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, path string) {
+		base, outside := escapeTree(t)
+		s := NewServer(nil, FSVideoContentService{FSDir: base})
+
+		// The handler sees the path after the client's escaping is undone
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = "/content/" + path
+		recorder := httptest.NewRecorder()
+		s.handleVideoContent(recorder, req)
+
+		if recorder.Body.String() == "secret" {
+			t.Fatalf("GET %q returned the secret", req.URL.Path)
+		}
+		if unsafeName(path) && recorder.Code == http.StatusOK {
+			t.Errorf("GET %q of an unsafe path succeeded", req.URL.Path)
+		}
+		checkOutside(t, outside)
+	})
+}
+
+func TestContentSymlinksAreNotFollowed(t *testing.T) {
+	base, outside := escapeTree(t)
+	s := NewServer(nil, FSVideoContentService{FSDir: base})
+
+	for _, path := range []string{"/content/link/secret", "/content/video/link"} {
+		recorder := httptest.NewRecorder()
+		s.handleVideoContent(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code == http.StatusOK {
+			t.Errorf("GET %s followed a symlink: %q", path, recorder.Body.String())
+		}
+	}
+	checkOutside(t, outside)
+}
+
+func TestDeleteVideo(t *testing.T) {
+	ctx := context.Background()
+	metadataService := SQLiteVideoMetadataService{DBPath: filepath.Join(t.TempDir(), "metadata.db")}
+	contentService := FSVideoContentService{FSDir: t.TempDir()}
+	s := NewServer(metadataService, contentService)
+	s.AdminAccess = AdminAccess{Tokens: []AdminToken{
+		{Name: "alice", Token: "operator-token", Role: RoleOperator},
+		{Name: "bob", Token: "viewer-token", Role: RoleViewer},
+	}}
+
+	if err := metadataService.Create(ctx, "video", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := contentService.Write(ctx, "video", "manifest.mpd", []byte("manifest")); err != nil {
+		t.Fatal(err)
+	}
+	// A video whose metadata is gone but whose content was left behind
+	if err := contentService.Write(ctx, "leftover", "manifest.mpd", []byte("manifest")); err != nil {
+		t.Fatal(err)
+	}
+
+	deleteVideo := func(videoId string, token string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/videos/"+videoId, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		s.handleVideo(recorder, req)
+		return recorder.Code
+	}
+
+	tests := []struct {
+		videoId string
+		token   string
+		want    int
+	}{
+		{"video", "", http.StatusUnauthorized},
+		{"video", "guess", http.StatusUnauthorized},
+		{"video", "viewer-token", http.StatusForbidden},
+		{"video", "operator-token", http.StatusNoContent},
+		{"video", "operator-token", http.StatusNotFound},
+		{"leftover", "operator-token", http.StatusNoContent},
+		{"..", "operator-token", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if got := deleteVideo(test.videoId, test.token); got != test.want {
+			t.Errorf("DELETE /videos/%s with token %q = %d, want %d", test.videoId, test.token, got, test.want)
+		}
+	}
+
+	if metadata, err := metadataService.Read(ctx, "video"); err != nil || metadata != nil {
+		t.Errorf("metadata left after delete: %v, %v", metadata, err)
+	}
+	for _, videoId := range []string{"video", "leftover"} {
+		if _, err := contentService.Read(ctx, videoId, "manifest.mpd"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("content of %s left after delete: %v", videoId, err)
+		}
+	}
+}
+
+func TestMakeImage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// A failed run leaves nothing behind, so the upload goes ahead without
+	// the image
+	poster := filepath.Join(dir, posterFile)
+	if err := os.WriteFile(poster, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	makeImage(ctx, "video", posterArgs(filepath.Join(dir, "missing.mp4"), poster))
+	if _, err := os.Stat(poster); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a failed poster was left behind: %v", err)
+	}
+
+	// makeImage finds the image in the last argument
+	for _, args := range [][]string{posterArgs("upload.mp4", "image"), previewArgs("upload.mp4", "image")} {
+		if args[len(args)-1] != "image" {
+			t.Errorf("ffmpeg arguments %v do not end with the image", args)
+		}
+	}
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	input := filepath.Join(dir, "upload.mp4")
+	out, err := exec.Command("ffmpeg", "-f", "lavfi", "-i", "testsrc=duration=2:size=320x240:rate=10", "-pix_fmt", "yuv420p", input).CombinedOutput()
+	if err != nil {
+		t.Fatalf("making a test video: %v\n%s", err, out)
+	}
+	tests := []struct {
+		file  string
+		args  []string
+		magic string
+	}{
+		{posterFile, posterArgs(input, filepath.Join(dir, posterFile)), "\xff\xd8\xff"},
+		{previewFile, previewArgs(input, filepath.Join(dir, previewFile)), "RIFF"},
+	}
+	for _, test := range tests {
+		makeImage(ctx, "video", test.args)
+		data, err := os.ReadFile(filepath.Join(dir, test.file))
+		if err != nil {
+			t.Errorf("making %s: %v", test.file, err)
+		} else if !strings.HasPrefix(string(data), test.magic) {
+			t.Errorf("%s starts with %q", test.file, data[:min(len(data), 4)])
+		}
+	}
+}
+
+func TestImageContent(t *testing.T) {
+	ctx := context.Background()
+	contentService := FSVideoContentService{FSDir: t.TempDir()}
+	s := NewServer(nil, contentService)
+	for _, filename := range []string{posterFile, previewFile, "manifest.mpd"} {
+		if err := contentService.Write(ctx, "video", filename, []byte(filename)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		videoId     string
+		filename    string
+		code        int
+		contentType string
+	}{
+		{"video", posterFile, http.StatusOK, "image/jpeg"},
+		{"video", previewFile, http.StatusOK, "image/webp"},
+		{"video", "manifest.mpd", http.StatusOK, "application/octet-stream"},
+		// Videos uploaded before images were made have none, and the page
+		// falls back when they are not found
+		{"older", posterFile, http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		s.handleVideoContent(recorder, httptest.NewRequest(http.MethodGet, "/content/"+test.videoId+"/"+test.filename, nil))
+		if recorder.Code != test.code {
+			t.Errorf("GET %s/%s = %d, want %d", test.videoId, test.filename, recorder.Code, test.code)
+		}
+		if contentType := recorder.Header().Get("Content-Type"); test.contentType != "" && contentType != test.contentType {
+			t.Errorf("GET %s/%s has content type %q, want %q", test.videoId, test.filename, contentType, test.contentType)
+		}
+	}
+}
+
+func TestIndexFallsBackToPoster(t *testing.T) {
+	ctx := context.Background()
+	metadataService := SQLiteVideoMetadataService{DBPath: filepath.Join(t.TempDir(), "metadata.db")}
+	if err := metadataService.Create(ctx, "video", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(metadataService, FSVideoContentService{FSDir: t.TempDir()})
+
+	recorder := httptest.NewRecorder()
+	s.handleIndex(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := recorder.Body.String()
+	for _, want := range []string{`src="/content/video/preview.webp"`, `data-poster="/content/video/poster.jpg"`, "this.src = this.dataset.poster"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("the index page does not contain %s", want)
+		}
+	}
+}
@@ -3,8 +3,9 @@
 package web
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -15,7 +16,7 @@ type SQLiteVideoMetadataService struct{
 	DBPath string
 }
 
-func (s SQLiteVideoMetadataService) OpenDB() (*sql.DB, error) {
+func (s SQLiteVideoMetadataService) OpenDB(ctx context.Context) (*sql.DB, error) {
 	var makeTable bool
 
 	// Check if DB exists
@@ -23,22 +24,22 @@ func (s SQLiteVideoMetadataService) OpenDB() (*sql.DB, error) {
 	if os.IsNotExist(err) {
 		makeTable = true
 	} else if err != nil {
-		log.Printf("Error while checking if SQLite database exists: %v", err)
+		slog.ErrorContext(ctx, "Error while checking if SQLite database exists", "err", err)
 		return nil, err
 	}
 
 	// Open DB
 	db, err := sql.Open("sqlite3", s.DBPath)
 	if err != nil {
-		log.Printf("Error while opening SQLite database: %v", err)
+		slog.ErrorContext(ctx, "Error while opening SQLite database", "err", err)
 		return nil, err
 	}
 
 	// If DB didn't exist before, create the table
 	if makeTable {
-		_, err = db.Exec("CREATE TABLE metadata (videoID TEXT NOT NULL PRIMARY KEY, uploadedAt TIMESTAMP);")
+		_, err = db.ExecContext(ctx, "CREATE TABLE metadata (videoID TEXT NOT NULL PRIMARY KEY, uploadedAt TIMESTAMP);")
 		if err != nil {
-			log.Printf("Error while trying to create table: %v", err)
+			slog.ErrorContext(ctx, "Error while trying to create table", "err", err)
 			return nil, err
 		}
 	}
@@ -46,22 +47,22 @@ func (s SQLiteVideoMetadataService) OpenDB() (*sql.DB, error) {
 	return db, nil
 }
 
-func (s SQLiteVideoMetadataService) Read(id string) (*VideoMetadata, error) {
-	db, err := s.OpenDB()
+func (s SQLiteVideoMetadataService) Read(ctx context.Context, id string) (*VideoMetadata, error) {
+	db, err := s.OpenDB(ctx)
 	if err != nil {
-		log.Printf("Error while opening SQLite database: %v", err)
+		slog.ErrorContext(ctx, "Error while opening SQLite database", "video_id", id, "err", err)
 		return nil, err
 	}
 	defer db.Close()
 
-	row := db.QueryRow("SELECT uploadedAt FROM metadata WHERE videoID = ?", id)
+	row := db.QueryRowContext(ctx, "SELECT uploadedAt FROM metadata WHERE videoID = ?", id)
 	
 	var uploadedAt time.Time
 	err = row.Scan(&uploadedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
-		log.Printf("Error while parsing rows (read): %v", err)
+		slog.ErrorContext(ctx, "Error while parsing rows (read)", "video_id", id, "err", err)
 		return nil, err
 	}
 	return &VideoMetadata{
@@ -70,17 +71,17 @@ func (s SQLiteVideoMetadataService) Read(id string) (*VideoMetadata, error) {
 		}, nil
 }
 
-func (s SQLiteVideoMetadataService) List() ([]VideoMetadata, error) {
-	db, err := s.OpenDB()
+func (s SQLiteVideoMetadataService) List(ctx context.Context) ([]VideoMetadata, error) {
+	db, err := s.OpenDB(ctx)
 	if err != nil {
-		log.Printf("Error while opening SQLite database: %v", err)
+		slog.ErrorContext(ctx, "Error while opening SQLite database", "err", err)
 		return nil, err
 	}
 	defer db.Close()
 
-	rows, err := db.Query("SELECT videoID, uploadedAt FROM metadata")
+	rows, err := db.QueryContext(ctx, "SELECT videoID, uploadedAt FROM metadata")
 	if err != nil {
-		log.Printf("Error while querying metadata (list): %v", err)
+		slog.ErrorContext(ctx, "Error while querying metadata (list)", "err", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -91,7 +92,7 @@ func (s SQLiteVideoMetadataService) List() ([]VideoMetadata, error) {
 		var uploadedAt time.Time
 		err := rows.Scan(&videoID, &uploadedAt)
 		if err != nil {
-			log.Printf("Error while parsing rows (list): %v", err)
+			slog.ErrorContext(ctx, "Error while parsing rows (list)", "err", err)
 			return nil, err
 		}
 		retSlice = append(retSlice, VideoMetadata{
@@ -103,17 +104,34 @@ func (s SQLiteVideoMetadataService) List() ([]VideoMetadata, error) {
 	return retSlice, nil
 }
 
-func (s SQLiteVideoMetadataService) Create(videoId string, uploadedAt time.Time) error {
-	db, err := s.OpenDB()
+func (s SQLiteVideoMetadataService) Create(ctx context.Context, videoId string, uploadedAt time.Time) error {
+	db, err := s.OpenDB(ctx)
 	if err != nil {
-		log.Printf("Error while opening SQLite database: %v", err)
+		slog.ErrorContext(ctx, "Error while opening SQLite database", "video_id", videoId, "err", err)
 		return err
 	}
 	defer db.Close()
 
-	_, err = db.Exec("INSERT INTO metadata (videoID, uploadedAt) VALUES (?, ?)", videoId, uploadedAt)
+	_, err = db.ExecContext(ctx, "INSERT INTO metadata (videoID, uploadedAt) VALUES (?, ?)", videoId, uploadedAt)
 	if err != nil {
-		log.Printf("Error while inserting metadata: %v", err)
+		slog.ErrorContext(ctx, "Error while inserting metadata", "video_id", videoId, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s SQLiteVideoMetadataService) Delete(ctx context.Context, videoId string) error {
+	db, err := s.OpenDB(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while opening SQLite database", "video_id", videoId, "err", err)
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "DELETE FROM metadata WHERE videoID = ?", videoId)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error while deleting metadata", "video_id", videoId, "err", err)
 		return err
 	}
 
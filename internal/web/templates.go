@@ -52,9 +52,10 @@ const indexHTML = `
         {{range .}}
         <div class="col">
           <div class="card h-100">
-            <div class="ratio ratio-16x9">
-              <!-- Small DASH preview player; muted and autoplaying -->
-              <video id="preview-{{.EscapedId}}" class="card-img-top" playsinline muted autoplay loop data-mpd="/content/{{.Id}}/manifest.mpd"></video>
+            <div class="ratio ratio-16x9 bg-body-secondary">
+              <!-- Animated preview, falling back to the poster, then to nothing for videos uploaded without them -->
+              <img class="card-img-top object-fit-cover" loading="lazy" alt="" src="/content/{{.EscapedId}}/preview.webp" data-poster="/content/{{.EscapedId}}/poster.jpg"
+                   onerror="if (this.dataset.poster) { this.src = this.dataset.poster; this.dataset.poster = ''; } else { this.style.visibility = 'hidden'; }" />
             </div>
             <div class="card-body">
               <h5 class="card-title text-truncate">{{.Id}}</h5>
@@ -70,32 +71,7 @@ const indexHTML = `
       {{end}}
     </div>
 
-    <script src="https://cdn.dashjs.org/latest/dash.all.min.js"></script>
     <script src="https://cdn.jsdelivr.net/npm/bootstrap@5.3.8/dist/js/bootstrap.bundle.min.js" integrity="sha384-FKyoEForCGlyvwx9Hj09JcYn3nv7wiPVlz7YYwJrWVcXK/BmnVDxM+D2scQbITxI" crossorigin="anonymous"></script>
-
-    <script>
-      // Initialize a small dash.js player for every preview video element that has a data-mpd attribute
-      (function(){
-        document.addEventListener('DOMContentLoaded', function(){
-          var videos = document.querySelectorAll('video[data-mpd]');
-          videos.forEach(function(v){
-            try {
-              var mpd = v.getAttribute('data-mpd');
-              var player = dashjs.MediaPlayer().create();
-              player.initialize(v, mpd, true);
-              // ensure muted so autoplay works across browsers
-              v.muted = true;
-              // small safety: attempt to play, but ignore failures
-              var p = v.play();
-              if (p && p.then) p.catch(function(e){});
-            } catch (e) {
-              // If dash.js fails for a preview, leave the card without an active preview
-              console.warn('preview init failed', e);
-            }
-          });
-        });
-      })();
-    </script>
   </body>
 </html>
 `
@@ -129,7 +105,7 @@ const videoHTML = `
         <div class="col-12">
           <div class="card video-card">
             <div class="ratio ratio-16x9">
-              <video id="dashPlayer" class="w-100 h-100" controls playsinline></video>
+              <video id="dashPlayer" class="w-100 h-100" controls playsinline poster="/content/{{.EscapedId}}/poster.jpg"></video>
             </div>
             <div class="card-body">
               <h4 class="card-title mb-1">{{.Id}}</h4>
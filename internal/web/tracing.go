@@ -0,0 +1,54 @@
+package web
+
+import (
+	"context"
+	"time"
+
+	"tritontube/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedMetadataService records a trace span for every call to a metadata
+// service.
+type tracedMetadataService struct {
+	VideoMetadataService
+}
+
+func (s tracedMetadataService) Read(ctx context.Context, id string) (*VideoMetadata, error) {
+	ctx, span := tracing.Start(ctx, "VideoMetadataService.Read", attribute.String("video_id", id))
+	metadata, err := s.VideoMetadataService.Read(ctx, id)
+	span.SetAttributes(attribute.Bool("found", metadata != nil))
+	tracing.End(span, err)
+	return metadata, err
+}
+
+func (s tracedMetadataService) List(ctx context.Context) ([]VideoMetadata, error) {
+	ctx, span := tracing.Start(ctx, "VideoMetadataService.List")
+	videos, err := s.VideoMetadataService.List(ctx)
+	span.SetAttributes(attribute.Int("videos", len(videos)))
+	tracing.End(span, err)
+	return videos, err
+}
+
+func (s tracedMetadataService) Create(ctx context.Context, videoId string, uploadedAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "VideoMetadataService.Create", attribute.String("video_id", videoId))
+	err := s.VideoMetadataService.Create(ctx, videoId, uploadedAt)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedMetadataService) Delete(ctx context.Context, videoId string) error {
+	ctx, span := tracing.Start(ctx, "VideoMetadataService.Delete", attribute.String("video_id", videoId))
+	err := s.VideoMetadataService.Delete(ctx, videoId)
+	tracing.End(span, err)
+	return err
+}
+
+// Shutdown shuts down the traced service if it needs to be.
+func (s tracedMetadataService) Shutdown(ctx context.Context) error {
+	if service, ok := s.VideoMetadataService.(shutdowner); ok {
+		return service.Shutdown(ctx)
+	}
+	return nil
+}
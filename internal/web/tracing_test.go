@@ -0,0 +1,56 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx := context.Background()
+	metadataService := SQLiteVideoMetadataService{DBPath: filepath.Join(t.TempDir(), "metadata.db")}
+	if err := metadataService.Create(ctx, "video", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(metadataService, FSVideoContentService{FSDir: t.TempDir()})
+
+	handler := instrument("/videos/", http.HandlerFunc(s.handleVideo))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/videos/video", nil))
+
+	var requestSpan, readSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "/videos/":
+			requestSpan = span
+		case "VideoMetadataService.Read":
+			readSpan = span
+		}
+	}
+	if requestSpan == nil || readSpan == nil {
+		t.Fatalf("recorded %d spans, want one for the request and one for reading the metadata", len(recorder.Ended()))
+	}
+	if readSpan.Parent().SpanID() != requestSpan.SpanContext().SpanID() {
+		t.Error("the metadata read is not under the request span")
+	}
+	attrs := attribute.NewSet(readSpan.Attributes()...)
+	if videoId, _ := attrs.Value("video_id"); videoId.AsString() != "video" {
+		t.Errorf("the metadata read span has video_id %q", videoId.AsString())
+	}
+	if found, _ := attrs.Value("found"); !found.AsBool() {
+		t.Error("the metadata read span does not record that the video was found")
+	}
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TranscodeProfile is one video quality uploads are converted to. Viewers'
+// players switch between profiles to suit their bandwidth.
+type TranscodeProfile struct {
+	Name string
+	// Height scales the video to this many lines, keeping its aspect ratio.
+	// Zero keeps the uploaded size.
+	Height int
+	// VideoBitrate is passed to ffmpeg, e.g. "3000k".
+	VideoBitrate string
+}
+
+// DefaultTranscodeProfiles convert uploads to a single quality at the
+// uploaded size.
+var DefaultTranscodeProfiles = []TranscodeProfile{{Name: "default", VideoBitrate: "3000k"}}
+
+// DefaultAudioBitrate is the audio bitrate used unless one is configured.
+const DefaultAudioBitrate = "128k"
+
+// ffmpegArgs builds the ffmpeg arguments converting input to MPEG-DASH, with
+// one video representation per profile and the manifest at manifestPath.
+func ffmpegArgs(input string, manifestPath string, profiles []TranscodeProfile, audioBitrate string) []string {
+	args := []string{
+		"-i", input, // input file
+		"-c:v", "libx264", // video codec
+		"-c:a", "aac", // audio codec
+		"-bf", "1", // max 1 b-frame
+		"-keyint_min", "120", // minimum keyframe interval
+		"-g", "120", // keyframe every 120 frames
+		"-sc_threshold", "0", // scene change threshold
+	}
+
+	if len(profiles) == 1 && profiles[0].Height == 0 {
+		args = append(args, "-b:v", profiles[0].VideoBitrate) // video bitrate
+	} else {
+		// Encode the video once per profile, alongside the audio if there is any
+		for range profiles {
+			args = append(args, "-map", "0:v:0")
+		}
+		args = append(args, "-map", "0:a:0?")
+		for i, profile := range profiles {
+			args = append(args, fmt.Sprintf("-b:v:%d", i), profile.VideoBitrate)
+			if profile.Height > 0 {
+				args = append(args, fmt.Sprintf("-filter:v:%d", i), "scale=-2:"+strconv.Itoa(profile.Height))
+			}
+		}
+		args = append(args, "-adaptation_sets", "id=0,streams=v id=1,streams=a")
+	}
+
+	return append(args,
+		"-b:a", audioBitrate, // audio bitrate
+		"-f", "dash", // dash format
+		"-use_timeline", "1", // use timeline
+		"-use_template", "1", // use template
+		"-init_seg_name", "init-$RepresentationID$.m4s", // init segment naming
+		"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s", // media segment naming
+		"-seg_duration", "4", // segment duration in seconds
+		manifestPath) // output file
+}
+
+// Images made from every upload alongside its MPEG-DASH files: a still poster
+// for the player, and a short animated preview for the list of videos.
+const (
+	posterFile  = "poster.jpg"
+	previewFile = "preview.webp"
+)
+
+// posterArgs builds the ffmpeg arguments saving a representative frame from
+// the start of input as a JPEG.
+func posterArgs(input string, output string) []string {
+	return []string{
+		"-i", input, // input file
+		"-vf", "thumbnail,scale=640:-2", // pick a typical frame, 640 pixels wide
+		"-frames:v", "1", // a single frame
+		"-q:v", "3", // JPEG quality
+		"-y", output, // output file
+	}
+}
+
+// previewArgs builds the ffmpeg arguments saving the first few seconds of
+// input as a small, silent, looping animated WebP.
+func previewArgs(input string, output string) []string {
+	return []string{
+		"-t", "3", // first 3 seconds
+		"-i", input, // input file
+		"-vf", "fps=10,scale=320:-2", // 10 frames per second, 320 pixels wide
+		"-an",             // no audio
+		"-c:v", "libwebp", // animated WebP
+		"-quality", "60", // WebP quality
+		"-loop", "0", // loop forever
+		"-y", output, // output file
+	}
+}
+
+// imageContentTypes are the content types of the images made from uploads.
+var imageContentTypes = map[string]string{
+	posterFile:  "image/jpeg",
+	previewFile: "image/webp",
+}